@@ -2,88 +2,181 @@ package aes
 
 import (
 	"encoding/binary"
-	"fmt"
 )
 
-// Encrypt encrypts the input bytes following the AES standard
-func Encrypt(in []byte, key []byte) []byte {
-	w := keyExpansion(key)
-	return cipher(in, w)
+// Encrypt encrypts the input bytes following the AES standard. The key must be
+// 16, 24 or 32 bytes long and the input exactly one block; anything else is
+// rejected with a KeySizeError or BlockSizeError.
+func Encrypt(in []byte, key []byte) ([]byte, error) {
+	return EncryptTrace(in, key, nil)
 }
 
-// Decrypt decrypts the input bytes following the AES standard
-func Decrypt(in []byte, key []byte) []byte {
-	w := keyExpansion(key)
-	return inverseCipher(in, w)
+// Decrypt decrypts the input bytes following the AES standard. The key must be
+// 16, 24 or 32 bytes long and the input exactly one block; anything else is
+// rejected with a KeySizeError or BlockSizeError.
+func Decrypt(in []byte, key []byte) ([]byte, error) {
+	return DecryptTrace(in, key, nil)
 }
 
-func cipher(in []byte, w []uint32) []byte {
-	fmt.Printf("CIPHER (ENCRYPT):\n")
-	fmt.Printf("round[ 0].input    %x\n", in)
-	state := toState(in)
+// EncryptTrace is Encrypt, reporting every intermediate value to t
+func EncryptTrace(in []byte, key []byte, t Tracer) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	if err := checkBlock(in); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, BlockSize)
+	cipher(out, in, keyExpansion(key), t)
+	return out, nil
+}
+
+// DecryptTrace is Decrypt, reporting every intermediate value to t
+func DecryptTrace(in []byte, key []byte, t Tracer) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	if err := checkBlock(in); err != nil {
+		return nil, err
+	}
 
+	out := make([]byte, BlockSize)
+	inverseCipher(out, in, keyExpansion(key), t)
+	return out, nil
+}
+
+// DecryptEquivalent decrypts the input bytes using the Equivalent Inverse
+// Cipher of FIPS 197 section 5.3.5. The result is the same as Decrypt.
+func DecryptEquivalent(in []byte, key []byte) ([]byte, error) {
+	return DecryptEquivalentTrace(in, key, nil)
+}
+
+// DecryptEquivalentTrace is DecryptEquivalent, reporting every intermediate
+// value to t
+func DecryptEquivalentTrace(in []byte, key []byte, t Tracer) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	if err := checkBlock(in); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, BlockSize)
+	eqInverseCipher(out, in, eqInvKeyExpansion(keyExpansion(key)), t)
+	return out, nil
+}
+
+// state is the FIPS 197 State stored column by column, so s[row+4*col] is
+// state[row][col] and the state has the same byte order as the input and
+// output blocks
+type state [BlockSize]byte
+
+// cipher encrypts the block in into out, which may be the same slice. out is
+// used as the state, so nothing is allocated unless t is set.
+func cipher(out, in []byte, w []uint32, t Tracer) {
 	Nr := (len(w) - 1) / 4
+	traceInput(t, Forward, Nr, in)
 
-	state = addRoundKey(state, w[:4])
-	fmt.Printf("round[ 0].k_sch    %s\n", wordsToString(w[:4]))
+	s := (*state)(out[:BlockSize])
+	copy(s[:], in)
+
+	addRoundKey(s, w[:4])
+	traceKey(t, 0, "k_sch", w[:4])
 
 	for i := 1; i <= Nr; i++ {
-		fmt.Printf("round[%2d].start    %s\n", i, stateToString(state))
-		state = subBytes(state)
-		fmt.Printf("round[%2d].s_box    %s\n", i, stateToString(state))
-		state = shiftRows(state)
-		fmt.Printf("round[%2d].s_row    %s\n", i, stateToString(state))
+		traceStep(t, i, "start", s)
+		subBytes(s)
+		traceStep(t, i, "s_box", s)
+		shiftRows(s)
+		traceStep(t, i, "s_row", s)
 
 		if i != Nr {
-			state = mixColumns(state)
-			fmt.Printf("round[%2d].m_col    %s\n", i, stateToString(state))
+			mixColumns(s)
+			traceStep(t, i, "m_col", s)
 		}
 
-		state = addRoundKey(state, w[i*4:(i+1)*4])
-		fmt.Printf("round[%2d].k_sch    %s\n", i, wordsToString(w[i*4:(i+1)*4]))
+		addRoundKey(s, w[i*4:(i+1)*4])
+		traceKey(t, i, "k_sch", w[i*4:(i+1)*4])
 	}
 
-	out := fromState(state)
-	fmt.Printf("round[%2d].output   %x\n\n", Nr, out)
-
-	return out
+	traceOutput(t, Nr, s)
 }
 
-func inverseCipher(in []byte, w []uint32) []byte {
-	fmt.Printf("INVERSE CIPHER (DECRYPT):\n")
-	fmt.Printf("round[ 0].iinput   %x\n", in)
-	state := toState(in)
-
+// inverseCipher decrypts the block in into out, which may be the same slice
+func inverseCipher(out, in []byte, w []uint32, t Tracer) {
 	Nr := (len(w) - 1) / 4
+	traceInput(t, Inverse, Nr, in)
+
+	s := (*state)(out[:BlockSize])
+	copy(s[:], in)
 
-	state = addRoundKey(state, w[Nr*4:(Nr+1)*4])
-	fmt.Printf("round[ 0].ik_sch   %s\n", wordsToString(w[Nr*4:(Nr+1)*4]))
+	addRoundKey(s, w[Nr*4:(Nr+1)*4])
+	traceKey(t, 0, "ik_sch", w[Nr*4:(Nr+1)*4])
 
 	for round := Nr - 1; round >= 0; round-- {
-		fmt.Printf("round[%2d].istart   %s\n", Nr-round, stateToString(state))
+		traceStep(t, Nr-round, "istart", s)
 
-		state = invShiftRows(state)
-		fmt.Printf("round[%2d].is_row   %s\n", Nr-round, stateToString(state))
+		invShiftRows(s)
+		traceStep(t, Nr-round, "is_row", s)
 
-		state = invSubBytes(state)
-		fmt.Printf("round[%2d].is_box   %s\n", Nr-round, stateToString(state))
+		invSubBytes(s)
+		traceStep(t, Nr-round, "is_box", s)
 
-		state = addRoundKey(state, w[round*4:(round+1)*4])
-		fmt.Printf("round[%2d].ik_sch   %s\n", Nr-round, wordsToString(w[round*4:(round+1)*4]))
+		addRoundKey(s, w[round*4:(round+1)*4])
+		traceKey(t, Nr-round, "ik_sch", w[round*4:(round+1)*4])
 
 		if round != 0 {
-			fmt.Printf("round[%2d].ik_add   %s\n", Nr-round, stateToString(state))
-			state = invMixColumns(state)
+			traceStep(t, Nr-round, "ik_add", s)
+			invMixColumns(s)
 		}
 	}
 
-	out := fromState(state)
-	fmt.Printf("round[%2d].ioutput  %x\n\n", Nr, out)
+	traceOutput(t, Nr, s)
+}
+
+// eqInverseCipher applies the steps of the inverse cipher in the same order as
+// the cipher, which works because InvSubBytes and InvShiftRows commute and
+// InvMixColumns has already been applied to the round keys in dw
+func eqInverseCipher(out, in []byte, dw []uint32, t Tracer) {
+	Nr := (len(dw) - 1) / 4
+	traceInput(t, EquivalentInverse, Nr, in)
+
+	s := (*state)(out[:BlockSize])
+	copy(s[:], in)
+
+	addRoundKey(s, dw[Nr*4:(Nr+1)*4])
+	traceKey(t, 0, "ik_sch", dw[Nr*4:(Nr+1)*4])
+
+	for round := Nr - 1; round >= 0; round-- {
+		traceStep(t, Nr-round, "istart", s)
+
+		invSubBytes(s)
+		traceStep(t, Nr-round, "is_box", s)
 
-	return fromState(state)
+		invShiftRows(s)
+		traceStep(t, Nr-round, "is_row", s)
+
+		if round != 0 {
+			invMixColumns(s)
+			traceStep(t, Nr-round, "im_col", s)
+		}
+
+		addRoundKey(s, dw[round*4:(round+1)*4])
+		traceKey(t, Nr-round, "ik_sch", dw[round*4:(round+1)*4])
+	}
+
+	traceOutput(t, Nr, s)
 }
 
 func keyExpansion(key []byte) []uint32 {
+	return expandKey(key, subWord)
+}
+
+// expandKey is the FIPS 197 KeyExpansion routine with SubWord supplied by the
+// caller, so that backends which must not index tables with key bytes can
+// substitute their own
+func expandKey(key []byte, subWord func(uint32) uint32) []uint32 {
 	Nk := len(key) / 4
 	Nr := Nk + 6
 
@@ -109,88 +202,97 @@ func keyExpansion(key []byte) []uint32 {
 	return w
 }
 
-func subBytes(state [][]byte) [][]byte {
-	for i, substate := range state {
-		for j, cell := range substate {
-			col := cell & 0x0f
-			cell = cell >> 4
-			row := cell & 0x0f
-			state[i][j] = sbox[row][col]
+// eqInvKeyExpansion derives the decryption key schedule dw of the Equivalent
+// Inverse Cipher by applying InvMixColumns to every round key but the first
+// and last
+func eqInvKeyExpansion(w []uint32) []uint32 {
+	dw := make([]uint32, len(w))
+	copy(dw, w)
+
+	Nr := (len(w) - 1) / 4
+
+	for round := 1; round < Nr; round++ {
+		words := dw[round*4 : (round+1)*4]
+
+		var s state
+		for i, word := range words {
+			binary.BigEndian.PutUint32(s[4*i:], word)
+		}
+		invMixColumns(&s)
+		for i := range words {
+			words[i] = binary.BigEndian.Uint32(s[4*i:])
 		}
 	}
-	return state
+
+	return dw
 }
 
-func shiftRows(state [][]byte) [][]byte {
-	for i, row := range state {
-		temp := make([]byte, len(row))
-		copy(temp, row)
-		for j := 0; j < 4; j++ {
-			state[i][j] = temp[(j+i)%4]
+func subBytes(s *state) {
+	for i, cell := range s {
+		col := cell & 0x0f
+		cell = cell >> 4
+		row := cell & 0x0f
+		s[i] = sbox[row][col]
+	}
+}
+
+func shiftRows(s *state) {
+	temp := *s
+	for row := 1; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			s[row+4*col] = temp[row+4*((col+row)%4)]
 		}
 	}
-	return state
 }
 
-func mixColumns(state [][]byte) [][]byte {
-	sp := makeEmptyState()
+func mixColumns(s *state) {
+	for col := 0; col < 16; col += 4 {
+		s0, s1, s2, s3 := s[col], s[col+1], s[col+2], s[col+3]
 
-	for col := 0; col < 4; col++ {
-		sp[0][col] = ffMultiply(0x02, state[0][col]) ^ ffMultiply(0x03, state[1][col]) ^ state[2][col] ^ state[3][col]
-		sp[1][col] = state[0][col] ^ ffMultiply(0x02, state[1][col]) ^ ffMultiply(0x03, state[2][col]) ^ state[3][col]
-		sp[2][col] = state[0][col] ^ state[1][col] ^ ffMultiply(0x02, state[2][col]) ^ ffMultiply(0x03, state[3][col])
-		sp[3][col] = ffMultiply(0x03, state[0][col]) ^ state[1][col] ^ state[2][col] ^ ffMultiply(0x02, state[3][col])
+		s[col] = ffMultiply(0x02, s0) ^ ffMultiply(0x03, s1) ^ s2 ^ s3
+		s[col+1] = s0 ^ ffMultiply(0x02, s1) ^ ffMultiply(0x03, s2) ^ s3
+		s[col+2] = s0 ^ s1 ^ ffMultiply(0x02, s2) ^ ffMultiply(0x03, s3)
+		s[col+3] = ffMultiply(0x03, s0) ^ s1 ^ s2 ^ ffMultiply(0x02, s3)
 	}
-
-	return sp
 }
 
-func addRoundKey(state [][]byte, w []uint32) [][]byte {
-
+func addRoundKey(s *state, w []uint32) {
 	for col := 0; col < 4; col++ {
-		keyfragment := make([]byte, 4)
-		binary.BigEndian.PutUint32(keyfragment, w[col])
-		for row := 0; row < 4; row++ {
-			state[row][col] = state[row][col] ^ keyfragment[row]
-		}
+		keyfragment := w[col]
+		s[4*col] ^= byte(keyfragment >> 24)
+		s[4*col+1] ^= byte(keyfragment >> 16)
+		s[4*col+2] ^= byte(keyfragment >> 8)
+		s[4*col+3] ^= byte(keyfragment)
 	}
-	return state
 }
 
-func invSubBytes(state [][]byte) [][]byte {
-	for i, substate := range state {
-		for j, cell := range substate {
-			col := cell & 0x0f
-			cell = cell >> 4
-			row := cell & 0x0f
-			state[i][j] = invsbox[row][col]
-		}
+func invSubBytes(s *state) {
+	for i, cell := range s {
+		col := cell & 0x0f
+		cell = cell >> 4
+		row := cell & 0x0f
+		s[i] = invsbox[row][col]
 	}
-	return state
 }
 
-func invShiftRows(state [][]byte) [][]byte {
-	for i, row := range state {
-		temp := make([]byte, len(row))
-		copy(temp, row)
-		for j := 0; j < 4; j++ {
-			state[i][(j+i)%4] = temp[j]
+func invShiftRows(s *state) {
+	temp := *s
+	for row := 1; row < 4; row++ {
+		for col := 0; col < 4; col++ {
+			s[row+4*((col+row)%4)] = temp[row+4*col]
 		}
 	}
-	return state
 }
 
-func invMixColumns(s [][]byte) [][]byte {
-	sp := makeEmptyState()
+func invMixColumns(s *state) {
+	for col := 0; col < 16; col += 4 {
+		s0, s1, s2, s3 := s[col], s[col+1], s[col+2], s[col+3]
 
-	for col := 0; col < 4; col++ {
-		sp[0][col] = ffMultiply(0x0e, s[0][col]) ^ ffMultiply(0x0b, s[1][col]) ^ ffMultiply(0x0d, s[2][col]) ^ ffMultiply(0x09, s[3][col])
-		sp[1][col] = ffMultiply(0x09, s[0][col]) ^ ffMultiply(0x0e, s[1][col]) ^ ffMultiply(0x0b, s[2][col]) ^ ffMultiply(0x0d, s[3][col])
-		sp[2][col] = ffMultiply(0x0d, s[0][col]) ^ ffMultiply(0x09, s[1][col]) ^ ffMultiply(0x0e, s[2][col]) ^ ffMultiply(0x0b, s[3][col])
-		sp[3][col] = ffMultiply(0x0b, s[0][col]) ^ ffMultiply(0x0d, s[1][col]) ^ ffMultiply(0x09, s[2][col]) ^ ffMultiply(0x0e, s[3][col])
+		s[col] = ffMultiply(0x0e, s0) ^ ffMultiply(0x0b, s1) ^ ffMultiply(0x0d, s2) ^ ffMultiply(0x09, s3)
+		s[col+1] = ffMultiply(0x09, s0) ^ ffMultiply(0x0e, s1) ^ ffMultiply(0x0b, s2) ^ ffMultiply(0x0d, s3)
+		s[col+2] = ffMultiply(0x0d, s0) ^ ffMultiply(0x09, s1) ^ ffMultiply(0x0e, s2) ^ ffMultiply(0x0b, s3)
+		s[col+3] = ffMultiply(0x0b, s0) ^ ffMultiply(0x0d, s1) ^ ffMultiply(0x09, s2) ^ ffMultiply(0x0e, s3)
 	}
-
-	return sp
 }
 
 func ffAdd(a, b byte) byte {
@@ -255,49 +357,10 @@ func rotWord(word uint32) uint32 {
 	return word + temp
 }
 
-func makeEmptyState() [][]byte {
-	return [][]byte{{0x00, 0x00, 0x00, 0x00},
-		{0x00, 0x00, 0x00, 0x00},
-		{0x00, 0x00, 0x00, 0x00},
-		{0x00, 0x00, 0x00, 0x00}}
-}
-
-func toState(in []byte) [][]byte {
-	s := makeEmptyState()
-
-	for row := 0; row < 4; row++ {
-		for col := 0; col < 4; col++ {
-			s[row][col] = in[row+4*col]
-		}
-	}
-
-	return s
-}
-
-func fromState(s [][]byte) []byte {
-	out := make([]byte, 16)
-	for row := 0; row < 4; row++ {
-		for col := 0; col < 4; col++ {
-			out[row+4*col] = s[row][col]
-		}
-	}
-	return out
-}
-
-func wordsToString(w []uint32) string {
-	s := ""
-	for _, word := range w {
-		s += fmt.Sprintf("%08x", word)
-	}
-	return s
-}
-
-func stateToString(state [][]byte) string {
-	s := ""
-	for col := 0; col < 4; col++ {
-		for row := 0; row < 4; row++ {
-			s += fmt.Sprintf("%02x", state[row][col])
-		}
+func wordsToBytes(w []uint32) []byte {
+	b := make([]byte, 4*len(w))
+	for i, word := range w {
+		binary.BigEndian.PutUint32(b[4*i:], word)
 	}
-	return s
+	return b
 }
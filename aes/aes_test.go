@@ -1,6 +1,7 @@
 package aes
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -52,30 +53,20 @@ func TestKeyExpansion(t *testing.T) {
 }
 
 func TestComponents(t *testing.T) {
-	state := [][]byte{{0x19, 0xa0, 0x9a, 0xe9},
-		{0x3d, 0xf4, 0xc6, 0xf8},
-		{0xe3, 0xe2, 0x8d, 0x48},
-		{0xbe, 0x2b, 0x2a, 0x08}}
-
-	subExpected := [][]byte{{0xd4, 0xe0, 0xb8, 0x1e},
-		{0x27, 0xbf, 0xb4, 0x41},
-		{0x11, 0x98, 0x5d, 0x52},
-		{0xae, 0xf1, 0xe5, 0x30}}
-
-	shiftExpected := [][]byte{{0xd4, 0xe0, 0xb8, 0x1e},
-		{0xbf, 0xb4, 0x41, 0x27},
-		{0x5d, 0x52, 0x11, 0x98},
-		{0x30, 0xae, 0xf1, 0xe5}}
-
-	mixExpected := [][]byte{{0x04, 0xe0, 0x48, 0x28},
-		{0x66, 0xcb, 0xf8, 0x06},
-		{0x81, 0x19, 0xd3, 0x26},
-		{0xe5, 0x9a, 0x7a, 0x4c}}
-
-	roundExpected := [][]byte{{0xa4, 0x68, 0x6b, 0x02},
-		{0x9c, 0x9f, 0x5b, 0x6a},
-		{0x7f, 0x35, 0xea, 0x50},
-		{0xf2, 0x2b, 0x43, 0x49}}
+	s := state{0x19, 0x3d, 0xe3, 0xbe, 0xa0, 0xf4, 0xe2, 0x2b,
+		0x9a, 0xc6, 0x8d, 0x2a, 0xe9, 0xf8, 0x48, 0x08}
+
+	subExpected := state{0xd4, 0x27, 0x11, 0xae, 0xe0, 0xbf, 0x98, 0xf1,
+		0xb8, 0xb4, 0x5d, 0xe5, 0x1e, 0x41, 0x52, 0x30}
+
+	shiftExpected := state{0xd4, 0xbf, 0x5d, 0x30, 0xe0, 0xb4, 0x52, 0xae,
+		0xb8, 0x41, 0x11, 0xf1, 0x1e, 0x27, 0x98, 0xe5}
+
+	mixExpected := state{0x04, 0x66, 0x81, 0xe5, 0xe0, 0xcb, 0x19, 0x9a,
+		0x48, 0xf8, 0xd3, 0x7a, 0x28, 0x06, 0x26, 0x4c}
+
+	roundExpected := state{0xa4, 0x9c, 0x7f, 0xf2, 0x68, 0x9f, 0x35, 0x2b,
+		0x6b, 0x5b, 0xea, 0x43, 0x02, 0x6a, 0x50, 0x49}
 
 	w := []uint32{0x2b7e1516, 0x28aed2a6, 0xabf71588, 0x09cf4f3c,
 		0xa0fafe17, 0x88542cb1, 0x23a33939, 0x2a6c7605,
@@ -89,10 +80,14 @@ func TestComponents(t *testing.T) {
 		0xac7766f3, 0x19fadc21, 0x28d12941, 0x575c006e,
 		0xd014f9a8, 0xc9ee2589, 0xe13f0cc8, 0xb6630ca6}
 
-	assert.Equal(t, subExpected, subBytes(state), "subBytes failed")
-	assert.Equal(t, shiftExpected, shiftRows(subExpected), "shiftRows failed")
-	assert.Equal(t, mixExpected, mixColumns(shiftExpected), "mixColumns failed")
-	assert.Equal(t, roundExpected, addRoundKey(mixExpected, w[4:8]), "addRoundKey failed")
+	subBytes(&s)
+	assert.Equal(t, subExpected, s, "subBytes failed")
+	shiftRows(&s)
+	assert.Equal(t, shiftExpected, s, "shiftRows failed")
+	mixColumns(&s)
+	assert.Equal(t, mixExpected, s, "mixColumns failed")
+	addRoundKey(&s, w[4:8])
+	assert.Equal(t, roundExpected, s, "addRoundKey failed")
 }
 
 func TestCipher(t *testing.T) {
@@ -114,51 +109,43 @@ func TestCipher(t *testing.T) {
 	result := []byte{0x39, 0x25, 0x84, 0x1d, 0x02, 0xdc, 0x09, 0xfb,
 		0xdc, 0x11, 0x85, 0x97, 0x19, 0x6a, 0x0b, 0x32}
 
-	out := cipher(in, w)
+	out := make([]byte, BlockSize)
+	cipher(out, in, w, nil)
 
 	assert.Equal(t, result, out)
 }
 
 func TestInvSubBytes(t *testing.T) {
-	input := [][]byte{{0xd4, 0xe0, 0xb8, 0x1e},
-		{0x27, 0xbf, 0xb4, 0x41},
-		{0x11, 0x98, 0x5d, 0x52},
-		{0xae, 0xf1, 0xe5, 0x30}}
+	input := state{0xd4, 0x27, 0x11, 0xae, 0xe0, 0xbf, 0x98, 0xf1,
+		0xb8, 0xb4, 0x5d, 0xe5, 0x1e, 0x41, 0x52, 0x30}
 
-	output := [][]byte{{0x19, 0xa0, 0x9a, 0xe9},
-		{0x3d, 0xf4, 0xc6, 0xf8},
-		{0xe3, 0xe2, 0x8d, 0x48},
-		{0xbe, 0x2b, 0x2a, 0x08}}
+	output := state{0x19, 0x3d, 0xe3, 0xbe, 0xa0, 0xf4, 0xe2, 0x2b,
+		0x9a, 0xc6, 0x8d, 0x2a, 0xe9, 0xf8, 0x48, 0x08}
 
-	assert.Equal(t, output, invSubBytes(input))
+	invSubBytes(&input)
+	assert.Equal(t, output, input)
 }
 
 func TestInvShiftRows(t *testing.T) {
-	input := [][]byte{{0xd4, 0xe0, 0xb8, 0x1e},
-		{0xbf, 0xb4, 0x41, 0x27},
-		{0x5d, 0x52, 0x11, 0x98},
-		{0x30, 0xae, 0xf1, 0xe5}}
+	input := state{0xd4, 0xbf, 0x5d, 0x30, 0xe0, 0xb4, 0x52, 0xae,
+		0xb8, 0x41, 0x11, 0xf1, 0x1e, 0x27, 0x98, 0xe5}
 
-	output := [][]byte{{0xd4, 0xe0, 0xb8, 0x1e},
-		{0x27, 0xbf, 0xb4, 0x41},
-		{0x11, 0x98, 0x5d, 0x52},
-		{0xae, 0xf1, 0xe5, 0x30}}
+	output := state{0xd4, 0x27, 0x11, 0xae, 0xe0, 0xbf, 0x98, 0xf1,
+		0xb8, 0xb4, 0x5d, 0xe5, 0x1e, 0x41, 0x52, 0x30}
 
-	assert.Equal(t, output, invShiftRows(input))
+	invShiftRows(&input)
+	assert.Equal(t, output, input)
 }
 
 func TestInvMixColumns(t *testing.T) {
-	input := [][]byte{{0x04, 0xe0, 0x48, 0x28},
-		{0x66, 0xcb, 0xf8, 0x06},
-		{0x81, 0x19, 0xd3, 0x26},
-		{0xe5, 0x9a, 0x7a, 0x4c}}
+	input := state{0x04, 0x66, 0x81, 0xe5, 0xe0, 0xcb, 0x19, 0x9a,
+		0x48, 0xf8, 0xd3, 0x7a, 0x28, 0x06, 0x26, 0x4c}
 
-	output := [][]byte{{0xd4, 0xe0, 0xb8, 0x1e},
-		{0xbf, 0xb4, 0x41, 0x27},
-		{0x5d, 0x52, 0x11, 0x98},
-		{0x30, 0xae, 0xf1, 0xe5}}
+	output := state{0xd4, 0xbf, 0x5d, 0x30, 0xe0, 0xb4, 0x52, 0xae,
+		0xb8, 0x41, 0x11, 0xf1, 0x1e, 0x27, 0x98, 0xe5}
 
-	assert.Equal(t, output, invMixColumns(input))
+	invMixColumns(&input)
+	assert.Equal(t, output, input)
 }
 
 func TestInvCipher(t *testing.T) {
@@ -180,7 +167,8 @@ func TestInvCipher(t *testing.T) {
 	expected := []byte{0x32, 0x43, 0xf6, 0xa8, 0x88, 0x5a, 0x30, 0x8d,
 		0x31, 0x31, 0x98, 0xa2, 0xe0, 0x37, 0x07, 0x34}
 
-	out := inverseCipher(in, w)
+	out := make([]byte, BlockSize)
+	inverseCipher(out, in, w, nil)
 
 	assert.Equal(t, expected, out)
 }
@@ -190,7 +178,10 @@ func TestCipher128(t *testing.T) {
 	key := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
 	expected := []byte{0x69, 0xc4, 0xe0, 0xd8, 0x6a, 0x7b, 0x04, 0x30, 0xd8, 0xcd, 0xb7, 0x80, 0x70, 0xb4, 0xc5, 0x5a}
 
-	assert.Equal(t, expected, Encrypt(in, key))
+	out, err := Encrypt(in, key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
 }
 
 func TestInvCipher128(t *testing.T) {
@@ -198,7 +189,10 @@ func TestInvCipher128(t *testing.T) {
 	key := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
 	in := []byte{0x69, 0xc4, 0xe0, 0xd8, 0x6a, 0x7b, 0x04, 0x30, 0xd8, 0xcd, 0xb7, 0x80, 0x70, 0xb4, 0xc5, 0x5a}
 
-	assert.Equal(t, expected, Decrypt(in, key))
+	out, err := Decrypt(in, key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
 }
 
 func TestCipher192(t *testing.T) {
@@ -207,7 +201,10 @@ func TestCipher192(t *testing.T) {
 		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}
 	expected := []byte{0xdd, 0xa9, 0x7c, 0xa4, 0x86, 0x4c, 0xdf, 0xe0, 0x6e, 0xaf, 0x70, 0xa0, 0xec, 0x0d, 0x71, 0x91}
 
-	assert.Equal(t, expected, Encrypt(in, key))
+	out, err := Encrypt(in, key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
 }
 
 func TestInvCipher192(t *testing.T) {
@@ -216,7 +213,10 @@ func TestInvCipher192(t *testing.T) {
 		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}
 	in := []byte{0xdd, 0xa9, 0x7c, 0xa4, 0x86, 0x4c, 0xdf, 0xe0, 0x6e, 0xaf, 0x70, 0xa0, 0xec, 0x0d, 0x71, 0x91}
 
-	assert.Equal(t, expected, Decrypt(in, key))
+	out, err := Decrypt(in, key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
 }
 
 func TestCipher256(t *testing.T) {
@@ -225,7 +225,10 @@ func TestCipher256(t *testing.T) {
 		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}
 	expected := []byte{0x8e, 0xa2, 0xb7, 0xca, 0x51, 0x67, 0x45, 0xbf, 0xea, 0xfc, 0x49, 0x90, 0x4b, 0x49, 0x60, 0x89}
 
-	assert.Equal(t, expected, Encrypt(in, key))
+	out, err := Encrypt(in, key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
 }
 
 func TestInvCipher256(t *testing.T) {
@@ -234,5 +237,109 @@ func TestInvCipher256(t *testing.T) {
 		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}
 	in := []byte{0x8e, 0xa2, 0xb7, 0xca, 0x51, 0x67, 0x45, 0xbf, 0xea, 0xfc, 0x49, 0x90, 0x4b, 0x49, 0x60, 0x89}
 
-	assert.Equal(t, expected, Decrypt(in, key))
+	out, err := Decrypt(in, key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
+}
+
+func TestBadKeySize(t *testing.T) {
+	in := make([]byte, 16)
+
+	for _, n := range []int{0, 7, 15, 17, 20, 33} {
+		_, err := Encrypt(in, make([]byte, n))
+		assert.Equal(t, KeySizeError(n), err)
+
+		_, err = Decrypt(in, make([]byte, n))
+		assert.Equal(t, KeySizeError(n), err)
+	}
+}
+
+func TestBadBlockSize(t *testing.T) {
+	key := make([]byte, 16)
+
+	for _, n := range []int{0, 1, 15, 17, 32} {
+		_, err := Encrypt(make([]byte, n), key)
+		assert.Equal(t, BlockSizeError(n), err)
+
+		_, err = Decrypt(make([]byte, n), key)
+		assert.Equal(t, BlockSizeError(n), err)
+	}
+
+	assert.EqualError(t, BlockSizeError(17), "aes: invalid block size 17")
+	assert.EqualError(t, KeySizeError(20), "aes: invalid key size 20")
+}
+
+func TestDecryptEquivalent(t *testing.T) {
+	expected := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	key := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}
+
+	cases := []struct {
+		keyLen int
+		in     []byte
+	}{
+		{16, []byte{0x69, 0xc4, 0xe0, 0xd8, 0x6a, 0x7b, 0x04, 0x30, 0xd8, 0xcd, 0xb7, 0x80, 0x70, 0xb4, 0xc5, 0x5a}},
+		{24, []byte{0xdd, 0xa9, 0x7c, 0xa4, 0x86, 0x4c, 0xdf, 0xe0, 0x6e, 0xaf, 0x70, 0xa0, 0xec, 0x0d, 0x71, 0x91}},
+		{32, []byte{0x8e, 0xa2, 0xb7, 0xca, 0x51, 0x67, 0x45, 0xbf, 0xea, 0xfc, 0x49, 0x90, 0x4b, 0x49, 0x60, 0x89}},
+	}
+
+	for _, c := range cases {
+		out, err := DecryptEquivalent(c.in, key[:c.keyLen])
+		assert.NoError(t, err)
+		assert.Equal(t, expected, out)
+	}
+}
+
+func TestEquivalentMatchesInverse(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+
+	for _, keyLen := range []int{16, 24, 32} {
+		key := make([]byte, keyLen)
+		in := make([]byte, 16)
+
+		for i := 0; i < 100; i++ {
+			rng.Read(key)
+			rng.Read(in)
+
+			w := keyExpansion(key)
+			expected := make([]byte, BlockSize)
+			out := make([]byte, BlockSize)
+
+			inverseCipher(expected, in, w, nil)
+			eqInverseCipher(out, in, eqInvKeyExpansion(w), nil)
+			assert.Equal(t, expected, out)
+		}
+	}
+}
+
+func TestEqInvKeyExpansion(t *testing.T) {
+	key := []byte{0x2b, 0x7e, 0x15, 0x16, 0x28, 0xae, 0xd2, 0xa6, 0xab, 0xf7, 0x15, 0x88, 0x09, 0xcf, 0x4f, 0x3c}
+	w := keyExpansion(key)
+	dw := eqInvKeyExpansion(w)
+
+	// the first and last round keys are used as they are
+	assert.Equal(t, w[:4], dw[:4])
+	assert.Equal(t, w[40:], dw[40:])
+
+	// InvMixColumns of the round 1 key a0fafe17 88542cb1 23a33939 2a6c7605
+	assert.Equal(t, []uint32{0x2b3708a7, 0xf262d405, 0xbc3ebdbf, 0x4b617d62}, dw[4:8])
+}
+
+func TestNoAllocations(t *testing.T) {
+	for _, backend := range []Backend{Reference, TTable, Bitsliced} {
+		c, err := NewCipherBackend(make([]byte, 32), backend)
+		assert.NoError(t, err)
+
+		buf := make([]byte, 4*BlockSize)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			c.Encrypt(buf, buf)
+			c.Decrypt(buf, buf)
+			c.DecryptEquivalent(buf, buf)
+			c.EncryptBlocks(buf, buf)
+			c.DecryptBlocks(buf, buf)
+		})
+		assert.Equal(t, 0.0, allocs, "%s backend allocates", backend)
+	}
 }
@@ -0,0 +1,269 @@
+package aes
+
+// The bitsliced backend never indexes memory or branches on key or data
+// bytes. Up to four blocks are spread over eight 64-bit planes so that every
+// bitwise operation on the planes works on all 64 state bytes at once, and
+// the S-box is computed as a circuit of ANDs and XORs instead of a lookup.
+
+// bsBlocks is the number of blocks processed together
+const bsBlocks = 4
+
+// bsState holds bsBlocks blocks bitsliced into eight planes. Bit l of plane k
+// is bit k of byte l%16 of block l/16, so with the FIPS 197 input ordering bit
+// 16*blk+4*col+row of each plane belongs to state[row][col] of block blk.
+type bsState [8]uint64
+
+// masks selecting the lanes of each state row
+const (
+	bsRow0 = 0x1111111111111111
+	bsRow1 = bsRow0 << 1
+	bsRow2 = bsRow0 << 2
+	bsRow3 = bsRow0 << 3
+)
+
+// bsPack bitslices n blocks of src into s, leaving unused lanes zero
+func bsPack(s *bsState, src []byte, n int) {
+	*s = bsState{}
+	for l := 0; l < BlockSize*n; l++ {
+		b := uint64(src[l])
+		for k := uint(0); k < 8; k++ {
+			s[k] |= (b >> k & 1) << uint(l)
+		}
+	}
+}
+
+// bsUnpack is the inverse of bsPack
+func bsUnpack(dst []byte, s *bsState, n int) {
+	for l := 0; l < BlockSize*n; l++ {
+		var b uint64
+		for k := uint(0); k < 8; k++ {
+			b |= (s[k] >> uint(l) & 1) << k
+		}
+		dst[l] = byte(b)
+	}
+}
+
+// bsMultiply multiplies every lane of a by the same lane of b in GF(2^8),
+// the bitsliced form of ffMultiply
+func bsMultiply(a, b *bsState) bsState {
+	var t [15]uint64
+	for i := 0; i < 8; i++ {
+		for j := 0; j < 8; j++ {
+			t[i+j] ^= a[i] & b[j]
+		}
+	}
+
+	// reduce modulo x^8 + x^4 + x^3 + x + 1 from the top down
+	for k := 14; k >= 8; k-- {
+		t[k-4] ^= t[k]
+		t[k-5] ^= t[k]
+		t[k-7] ^= t[k]
+		t[k-8] ^= t[k]
+	}
+
+	var r bsState
+	copy(r[:], t[:8])
+	return r
+}
+
+// bsInverse computes the multiplicative inverse of every lane as x^254, which
+// also maps 0 to 0 as SubBytes requires
+func bsInverse(x *bsState) bsState {
+	y := bsMultiply(x, x)
+	r := y
+	for i := 0; i < 6; i++ {
+		y = bsMultiply(&y, &y)
+		r = bsMultiply(&r, &y)
+	}
+	return r
+}
+
+func bsSubBytes(s *bsState) {
+	b := bsInverse(s)
+
+	// affine transformation of FIPS 197 equation 5.1 with c = 0x63
+	for i := 0; i < 8; i++ {
+		s[i] = b[i] ^ b[(i+4)%8] ^ b[(i+5)%8] ^ b[(i+6)%8] ^ b[(i+7)%8]
+	}
+	s[0] = ^s[0]
+	s[1] = ^s[1]
+	s[5] = ^s[5]
+	s[6] = ^s[6]
+}
+
+func bsInvSubBytes(s *bsState) {
+	// inverse of the affine transformation, which is a rotation by 1, 3 and
+	// 6 bits and the constant 0x05
+	var b bsState
+	for i := 0; i < 8; i++ {
+		b[i] = s[(i+7)%8] ^ s[(i+5)%8] ^ s[(i+2)%8]
+	}
+	b[0] = ^b[0]
+	b[2] = ^b[2]
+
+	*s = bsInverse(&b)
+}
+
+// bsRotateColumns rotates the 16 lanes of every block right by k lanes, that
+// is moves each byte k/4 columns to the left
+func bsRotateColumns(x uint64, k uint) uint64 {
+	lo := uint64(0xffff>>k) * 0x0001000100010001
+	return (x>>k)&lo | (x<<(16-k))&^lo
+}
+
+func bsShiftRows(s *bsState) {
+	for i, x := range s {
+		s[i] = x&bsRow0 | bsRotateColumns(x&bsRow1, 4) | bsRotateColumns(x&bsRow2, 8) | bsRotateColumns(x&bsRow3, 12)
+	}
+}
+
+func bsInvShiftRows(s *bsState) {
+	for i, x := range s {
+		s[i] = x&bsRow0 | bsRotateColumns(x&bsRow1, 12) | bsRotateColumns(x&bsRow2, 8) | bsRotateColumns(x&bsRow3, 4)
+	}
+}
+
+// bsRotateRows moves every byte of each column up one row, so that lane row r
+// holds what was in row r+1
+func bsRotateRows(x uint64) uint64 {
+	return (x>>1)&(bsRow0|bsRow1|bsRow2) | (x<<3)&bsRow3
+}
+
+// bsXtime is xtime on every lane
+func bsXtime(s *bsState) bsState {
+	return bsState{s[7], s[0] ^ s[7], s[1], s[2] ^ s[7], s[3] ^ s[7], s[4], s[5], s[6]}
+}
+
+func bsMixColumns(s *bsState) {
+	// s'[r] = {02}s[r] + {03}s[r+1] + s[r+2] + s[r+3]
+	//       = {02}(s[r] + s[r+1]) + s[r+1] + s[r+2] + s[r+3]
+	var a, b bsState
+	for i, x := range s {
+		r1 := bsRotateRows(x)
+		r2 := bsRotateRows(r1)
+		r3 := bsRotateRows(r2)
+		a[i] = x ^ r1
+		b[i] = r1 ^ r2 ^ r3
+	}
+
+	a = bsXtime(&a)
+	for i := range s {
+		s[i] = a[i] ^ b[i]
+	}
+}
+
+func bsInvMixColumns(s *bsState) {
+	// InvMixColumns is MixColumns after adding {04}(s[r] + s[r+2]) to each row
+	var t bsState
+	for i, x := range s {
+		t[i] = x ^ bsRotateRows(bsRotateRows(x))
+	}
+	t = bsXtime(&t)
+	t = bsXtime(&t)
+
+	for i := range s {
+		s[i] ^= t[i]
+	}
+	bsMixColumns(s)
+}
+
+func bsAddRoundKey(s *bsState, k *bsState) {
+	for i := range s {
+		s[i] ^= k[i]
+	}
+}
+
+// bsSubWord is subWord computed with the S-box circuit
+func bsSubWord(word uint32) uint32 {
+	var b [BlockSize]byte
+	b[0] = byte(word >> 24)
+	b[1] = byte(word >> 16)
+	b[2] = byte(word >> 8)
+	b[3] = byte(word)
+
+	var s bsState
+	bsPack(&s, b[:], 1)
+	bsSubBytes(&s)
+	bsUnpack(b[:], &s, 1)
+
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// bsKeyExpansion expands key without table lookups and returns every round
+// key bitsliced and repeated for each of the bsBlocks blocks
+func bsKeyExpansion(key []byte) []bsState {
+	w := expandKey(key, bsSubWord)
+	rk := make([]bsState, len(w)/4)
+
+	var b [bsBlocks * BlockSize]byte
+	for round := range rk {
+		copy(b[:BlockSize], wordsToBytes(w[round*4:(round+1)*4]))
+		for blk := 1; blk < bsBlocks; blk++ {
+			copy(b[blk*BlockSize:], b[:BlockSize])
+		}
+		bsPack(&rk[round], b[:], bsBlocks)
+	}
+
+	return rk
+}
+
+// encryptBlocksBitsliced encrypts every whole block of src into dst
+func encryptBlocksBitsliced(rk []bsState, dst, src []byte) {
+	Nr := len(rk) - 1
+
+	for len(src) >= BlockSize {
+		n := len(src) / BlockSize
+		if n > bsBlocks {
+			n = bsBlocks
+		}
+
+		var s bsState
+		bsPack(&s, src, n)
+
+		bsAddRoundKey(&s, &rk[0])
+		for round := 1; round < Nr; round++ {
+			bsSubBytes(&s)
+			bsShiftRows(&s)
+			bsMixColumns(&s)
+			bsAddRoundKey(&s, &rk[round])
+		}
+		bsSubBytes(&s)
+		bsShiftRows(&s)
+		bsAddRoundKey(&s, &rk[Nr])
+
+		bsUnpack(dst, &s, n)
+		src = src[n*BlockSize:]
+		dst = dst[n*BlockSize:]
+	}
+}
+
+// decryptBlocksBitsliced decrypts every whole block of src into dst with the
+// Inverse Cipher
+func decryptBlocksBitsliced(rk []bsState, dst, src []byte) {
+	Nr := len(rk) - 1
+
+	for len(src) >= BlockSize {
+		n := len(src) / BlockSize
+		if n > bsBlocks {
+			n = bsBlocks
+		}
+
+		var s bsState
+		bsPack(&s, src, n)
+
+		bsAddRoundKey(&s, &rk[Nr])
+		for round := Nr - 1; round > 0; round-- {
+			bsInvShiftRows(&s)
+			bsInvSubBytes(&s)
+			bsAddRoundKey(&s, &rk[round])
+			bsInvMixColumns(&s)
+		}
+		bsInvShiftRows(&s)
+		bsInvSubBytes(&s)
+		bsAddRoundKey(&s, &rk[0])
+
+		bsUnpack(dst, &s, n)
+		src = src[n*BlockSize:]
+		dst = dst[n*BlockSize:]
+	}
+}
@@ -0,0 +1,141 @@
+package aes
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitslicedSubBytes(t *testing.T) {
+	// all 256 byte values, 64 lanes at a time
+	for base := 0; base < 256; base += bsBlocks * BlockSize {
+		in := make([]byte, bsBlocks*BlockSize)
+		for i := range in {
+			in[i] = byte(base + i)
+		}
+
+		var s bsState
+		out := make([]byte, len(in))
+
+		bsPack(&s, in, bsBlocks)
+		bsSubBytes(&s)
+		bsUnpack(out, &s, bsBlocks)
+		for i, b := range in {
+			assert.Equal(t, sbox[b>>4][b&0x0f], out[i], "S-box of %02x", b)
+		}
+
+		bsPack(&s, in, bsBlocks)
+		bsInvSubBytes(&s)
+		bsUnpack(out, &s, bsBlocks)
+		for i, b := range in {
+			assert.Equal(t, invsbox[b>>4][b&0x0f], out[i], "inverse S-box of %02x", b)
+		}
+	}
+}
+
+func TestBitslicedComponents(t *testing.T) {
+	// the FIPS 197 Appendix B round 1 states, in input order
+	start := []byte{0x19, 0x3d, 0xe3, 0xbe, 0xa0, 0xf4, 0xe2, 0x2b, 0x9a, 0xc6, 0x8d, 0x2a, 0xe9, 0xf8, 0x48, 0x08}
+	sub := []byte{0xd4, 0x27, 0x11, 0xae, 0xe0, 0xbf, 0x98, 0xf1, 0xb8, 0xb4, 0x5d, 0xe5, 0x1e, 0x41, 0x52, 0x30}
+	shift := []byte{0xd4, 0xbf, 0x5d, 0x30, 0xe0, 0xb4, 0x52, 0xae, 0xb8, 0x41, 0x11, 0xf1, 0x1e, 0x27, 0x98, 0xe5}
+	mix := []byte{0x04, 0x66, 0x81, 0xe5, 0xe0, 0xcb, 0x19, 0x9a, 0x48, 0xf8, 0xd3, 0x7a, 0x28, 0x06, 0x26, 0x4c}
+
+	check := func(in []byte, f func(*bsState), expected []byte, name string) {
+		var s bsState
+		out := make([]byte, BlockSize)
+
+		bsPack(&s, in, 1)
+		f(&s)
+		bsUnpack(out, &s, 1)
+		assert.Equal(t, expected, out, name)
+	}
+
+	check(start, bsSubBytes, sub, "bsSubBytes")
+	check(sub, bsShiftRows, shift, "bsShiftRows")
+	check(shift, bsMixColumns, mix, "bsMixColumns")
+	check(sub, bsInvSubBytes, start, "bsInvSubBytes")
+	check(shift, bsInvShiftRows, sub, "bsInvShiftRows")
+	check(mix, bsInvMixColumns, shift, "bsInvMixColumns")
+}
+
+func TestBitslicedKeyExpansion(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+
+	for _, keyLen := range []int{16, 24, 32} {
+		key := make([]byte, keyLen)
+		rng.Read(key)
+
+		assert.Equal(t, keyExpansion(key), expandKey(key, bsSubWord))
+	}
+}
+
+func TestBitslicedMatchesReference(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+
+	for _, keyLen := range []int{16, 24, 32} {
+		key := make([]byte, keyLen)
+		rng.Read(key)
+
+		reference, err := NewCipher(key)
+		assert.NoError(t, err)
+		bitsliced, err := NewCipherBackend(key, Bitsliced)
+		assert.NoError(t, err)
+
+		// every block count up to two full batches and a partial one
+		for n := 1; n <= 2*bsBlocks+1; n++ {
+			in := make([]byte, n*BlockSize)
+			rng.Read(in)
+
+			expected := make([]byte, len(in))
+			out := make([]byte, len(in))
+
+			reference.EncryptBlocks(expected, in)
+			bitsliced.EncryptBlocks(out, in)
+			assert.Equal(t, expected, out)
+
+			bitsliced.DecryptBlocks(out, out)
+			assert.Equal(t, in, out)
+		}
+
+		in := make([]byte, BlockSize)
+		rng.Read(in)
+		expected := make([]byte, BlockSize)
+		out := make([]byte, BlockSize)
+
+		reference.Encrypt(expected, in)
+		bitsliced.Encrypt(out, in)
+		assert.Equal(t, expected, out)
+
+		reference.Decrypt(expected, in)
+		bitsliced.Decrypt(out, in)
+		assert.Equal(t, expected, out)
+	}
+}
+
+func TestEncryptBlocksPartial(t *testing.T) {
+	c, err := NewCipherBackend(make([]byte, 16), Bitsliced)
+	assert.NoError(t, err)
+
+	assert.Panics(t, func() { c.EncryptBlocks(make([]byte, 32), make([]byte, 31)) })
+	assert.Panics(t, func() { c.DecryptBlocks(make([]byte, 16), make([]byte, 32)) })
+}
+
+func BenchmarkEncryptBlocks(b *testing.B) {
+	for _, backend := range []Backend{TTable, Bitsliced} {
+		b.Run(backend.String(), func(b *testing.B) {
+			c, err := NewCipherBackend(make([]byte, 16), backend)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			buf := make([]byte, bsBlocks*BlockSize)
+			b.SetBytes(int64(len(buf)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				c.EncryptBlocks(buf, buf)
+			}
+		})
+	}
+}
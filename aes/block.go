@@ -0,0 +1,185 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"strconv"
+)
+
+// BlockSize is the AES block size in bytes
+const BlockSize = 16
+
+// Backend selects how a Cipher computes the AES rounds. Every backend gives
+// the same results.
+type Backend int
+
+const (
+	// Reference follows FIPS 197 one transformation at a time and is the
+	// only backend that reports to a Tracer
+	Reference Backend = iota
+	// TTable merges SubBytes, ShiftRows and MixColumns into four 32-bit table
+	// lookups per column, which is much faster
+	TTable
+	// Bitsliced processes four blocks at a time with the S-box computed as a
+	// boolean circuit. It never branches on or indexes memory with key or
+	// data bytes, so it is safe from cache-timing attacks.
+	Bitsliced
+)
+
+func (b Backend) String() string {
+	switch b {
+	case Reference:
+		return "Reference"
+	case TTable:
+		return "TTable"
+	case Bitsliced:
+		return "Bitsliced"
+	}
+	return "Backend(" + strconv.Itoa(int(b)) + ")"
+}
+
+// Cipher is an AES block cipher whose key schedule is expanded once in
+// NewCipher and reused for every block. It implements crypto/cipher.Block.
+type Cipher struct {
+	w       []uint32
+	dw      []uint32
+	rk      []bsState
+	backend Backend
+	tracer  Tracer
+}
+
+var _ gocipher.Block = (*Cipher)(nil)
+
+// NewCipher expands the key and returns a Cipher that can encrypt and decrypt
+// any number of blocks with it. The key must be 16, 24 or 32 bytes long to
+// select AES-128, AES-192 or AES-256, otherwise a KeySizeError is returned.
+func NewCipher(key []byte) (*Cipher, error) {
+	return NewCipherBackend(key, Reference)
+}
+
+// NewCipherBackend is NewCipher with the rounds computed by the given Backend
+func NewCipherBackend(key []byte, b Backend) (*Cipher, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	switch b {
+	case Reference, TTable:
+	case Bitsliced:
+		// the other key schedules are computed with table lookups and
+		// ffMultiply, so they are not kept at all
+		return &Cipher{rk: bsKeyExpansion(key), backend: b}, nil
+	default:
+		return nil, BackendError(b)
+	}
+
+	w := keyExpansion(key)
+	return &Cipher{w: w, dw: eqInvKeyExpansion(w), backend: b}, nil
+}
+
+// SetTracer makes every subsequent Encrypt and Decrypt report its intermediate
+// values to t. A nil Tracer turns tracing back off. Only the Reference
+// backend reports to a Tracer.
+func (c *Cipher) SetTracer(t Tracer) {
+	c.tracer = t
+}
+
+// BlockSize returns the AES block size in bytes
+func (c *Cipher) BlockSize() int {
+	return BlockSize
+}
+
+// Encrypt encrypts the first block of src into dst. dst and src may overlap.
+func (c *Cipher) Encrypt(dst, src []byte) {
+	checkBlocks(dst, src)
+
+	switch c.backend {
+	case TTable:
+		encryptBlockTTable(c.w, dst, src)
+	case Bitsliced:
+		encryptBlocksBitsliced(c.rk, dst[:BlockSize], src[:BlockSize])
+	default:
+		cipher(dst[:BlockSize], src[:BlockSize], c.w, c.tracer)
+	}
+}
+
+// Decrypt decrypts the first block of src into dst. dst and src may overlap.
+func (c *Cipher) Decrypt(dst, src []byte) {
+	checkBlocks(dst, src)
+
+	switch c.backend {
+	case TTable:
+		decryptBlockTTable(c.dw, dst, src)
+	case Bitsliced:
+		decryptBlocksBitsliced(c.rk, dst[:BlockSize], src[:BlockSize])
+	default:
+		inverseCipher(dst[:BlockSize], src[:BlockSize], c.w, c.tracer)
+	}
+}
+
+// DecryptEquivalent decrypts the first block of src into dst with the
+// Equivalent Inverse Cipher and the decryption key schedule precomputed by
+// NewCipher. dst and src may overlap.
+func (c *Cipher) DecryptEquivalent(dst, src []byte) {
+	checkBlocks(dst, src)
+
+	switch c.backend {
+	case TTable:
+		decryptBlockTTable(c.dw, dst, src)
+	case Bitsliced:
+		decryptBlocksBitsliced(c.rk, dst[:BlockSize], src[:BlockSize])
+	default:
+		eqInverseCipher(dst[:BlockSize], src[:BlockSize], c.dw, c.tracer)
+	}
+}
+
+// EncryptBlocks encrypts every block of src into dst. The Bitsliced backend
+// encrypts four blocks at a time, the others one after another. src must be a
+// whole number of blocks, dst at least as long, and the two must overlap
+// entirely or not at all.
+func (c *Cipher) EncryptBlocks(dst, src []byte) {
+	checkCryptBlocks(dst, src, BlockSize)
+
+	if c.backend == Bitsliced {
+		encryptBlocksBitsliced(c.rk, dst, src)
+		return
+	}
+	for i := 0; i < len(src); i += BlockSize {
+		c.Encrypt(dst[i:], src[i:])
+	}
+}
+
+// DecryptBlocks decrypts every block of src into dst, with the same
+// requirements as EncryptBlocks
+func (c *Cipher) DecryptBlocks(dst, src []byte) {
+	checkCryptBlocks(dst, src, BlockSize)
+
+	if c.backend == Bitsliced {
+		decryptBlocksBitsliced(c.rk, dst, src)
+		return
+	}
+	for i := 0; i < len(src); i += BlockSize {
+		c.Decrypt(dst[i:], src[i:])
+	}
+}
+
+// checkCryptBlocks panics like the crypto/cipher block modes when src is not
+// a whole number of blocks or dst is too short to hold the result
+func checkCryptBlocks(dst, src []byte, blockSize int) {
+	if len(src)%blockSize != 0 {
+		panic("aes: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("aes: output smaller than input")
+	}
+}
+
+// checkBlocks panics the same way crypto/aes does when a caller of the
+// crypto/cipher.Block methods hands us less than a full block
+func checkBlocks(dst, src []byte) {
+	if len(src) < BlockSize {
+		panic("aes: input not full block")
+	}
+	if len(dst) < BlockSize {
+		panic("aes: output not full block")
+	}
+}
@@ -0,0 +1,62 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCipher(t *testing.T) {
+	in := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	key := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}
+
+	cases := []struct {
+		keyLen   int
+		expected []byte
+	}{
+		{16, []byte{0x69, 0xc4, 0xe0, 0xd8, 0x6a, 0x7b, 0x04, 0x30, 0xd8, 0xcd, 0xb7, 0x80, 0x70, 0xb4, 0xc5, 0x5a}},
+		{24, []byte{0xdd, 0xa9, 0x7c, 0xa4, 0x86, 0x4c, 0xdf, 0xe0, 0x6e, 0xaf, 0x70, 0xa0, 0xec, 0x0d, 0x71, 0x91}},
+		{32, []byte{0x8e, 0xa2, 0xb7, 0xca, 0x51, 0x67, 0x45, 0xbf, 0xea, 0xfc, 0x49, 0x90, 0x4b, 0x49, 0x60, 0x89}},
+	}
+
+	for _, c := range cases {
+		aesCipher, err := NewCipher(key[:c.keyLen])
+		assert.NoError(t, err)
+
+		var block gocipher.Block = aesCipher
+		assert.Equal(t, BlockSize, block.BlockSize())
+
+		out := make([]byte, BlockSize)
+		block.Encrypt(out, in)
+		assert.Equal(t, c.expected, out)
+
+		// decrypt in place to make sure overlapping dst and src are handled
+		block.Decrypt(out, out)
+		assert.Equal(t, in, out)
+	}
+}
+
+func TestNewCipherBadKey(t *testing.T) {
+	_, err := NewCipher(make([]byte, 20))
+	assert.Equal(t, KeySizeError(20), err)
+}
+
+func TestCipherShortBlock(t *testing.T) {
+	block, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	assert.Panics(t, func() { block.Encrypt(make([]byte, 16), make([]byte, 15)) })
+	assert.Panics(t, func() { block.Decrypt(make([]byte, 15), make([]byte, 16)) })
+}
+
+// decodeHex turns the hex strings test vectors are published as into bytes
+func decodeHex(t testing.TB, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,157 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/rand"
+)
+
+// Cipher Block Chaining mode (SP 800-38A section 6.2) XORs every plaintext
+// block with the previous ciphertext block, or the IV for the first block,
+// before encrypting it
+
+type cbc struct {
+	b         gocipher.Block
+	blockSize int
+	iv        []byte
+	tmp       []byte
+}
+
+func newCBC(b gocipher.Block, iv []byte) (*cbc, error) {
+	if len(iv) != b.BlockSize() {
+		return nil, IVSizeError(len(iv))
+	}
+
+	return &cbc{
+		b:         b,
+		blockSize: b.BlockSize(),
+		iv:        append([]byte(nil), iv...),
+		tmp:       make([]byte, b.BlockSize()),
+	}, nil
+}
+
+// RandomIV returns a BlockSize byte IV read from crypto/rand. CBC needs an IV
+// that is unpredictable to an attacker, and a fresh random IV per message is
+// the easy way to get one.
+func RandomIV() ([]byte, error) {
+	iv := make([]byte, BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	return iv, nil
+}
+
+type cbcEncrypter cbc
+
+// NewCBCEncrypter returns a crypto/cipher.BlockMode which encrypts in Cipher
+// Block Chaining mode, starting from iv. iv must be one block long, otherwise
+// an IVSizeError is returned. Successive calls to CryptBlocks carry on the
+// chain where the previous one stopped.
+func NewCBCEncrypter(b gocipher.Block, iv []byte) (gocipher.BlockMode, error) {
+	x, err := newCBC(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return (*cbcEncrypter)(x), nil
+}
+
+func (x *cbcEncrypter) BlockSize() int {
+	return x.blockSize
+}
+
+func (x *cbcEncrypter) CryptBlocks(dst, src []byte) {
+	checkCryptBlocks(dst, src, x.blockSize)
+
+	iv := x.iv
+	for len(src) > 0 {
+		for i := 0; i < x.blockSize; i++ {
+			dst[i] = src[i] ^ iv[i]
+		}
+		x.b.Encrypt(dst[:x.blockSize], dst[:x.blockSize])
+
+		iv = dst[:x.blockSize]
+		src = src[x.blockSize:]
+		dst = dst[x.blockSize:]
+	}
+
+	copy(x.iv, iv)
+}
+
+type cbcDecrypter cbc
+
+// NewCBCDecrypter returns a crypto/cipher.BlockMode which decrypts in Cipher
+// Block Chaining mode, starting from iv
+func NewCBCDecrypter(b gocipher.Block, iv []byte) (gocipher.BlockMode, error) {
+	x, err := newCBC(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return (*cbcDecrypter)(x), nil
+}
+
+func (x *cbcDecrypter) BlockSize() int {
+	return x.blockSize
+}
+
+func (x *cbcDecrypter) CryptBlocks(dst, src []byte) {
+	checkCryptBlocks(dst, src, x.blockSize)
+	if len(src) == 0 {
+		return
+	}
+
+	// Work from the last block back to the first so that each ciphertext
+	// block is still there to be XORed in when dst and src are the same.
+	// The last ciphertext block is the IV of the next call.
+	n := x.blockSize
+	copy(x.tmp, src[len(src)-n:])
+
+	for end := len(src); end > 0; end -= n {
+		start := end - n
+
+		var prev []byte
+		if start == 0 {
+			prev = x.iv
+		} else {
+			prev = src[start-n : start]
+		}
+
+		x.b.Decrypt(dst[start:end], src[start:end])
+		for i := 0; i < n; i++ {
+			dst[start+i] ^= prev[i]
+		}
+	}
+
+	x.iv, x.tmp = x.tmp, x.iv
+}
+
+// EncryptCBC pads plaintext with p and encrypts it in CBC mode from iv
+func EncryptCBC(b gocipher.Block, iv, plaintext []byte, p Padding) ([]byte, error) {
+	mode, err := NewCBCEncrypter(b, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := p.Pad(plaintext, b.BlockSize())
+	if len(padded)%b.BlockSize() != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	out := make([]byte, len(padded))
+	mode.CryptBlocks(out, padded)
+	return out, nil
+}
+
+// DecryptCBC decrypts ciphertext in CBC mode from iv and removes the padding p
+func DecryptCBC(b gocipher.Block, iv, ciphertext []byte, p Padding) ([]byte, error) {
+	mode, err := NewCBCDecrypter(b, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext)%b.BlockSize() != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	out := make([]byte, len(ciphertext))
+	mode.CryptBlocks(out, ciphertext)
+	return p.Unpad(out, b.BlockSize())
+}
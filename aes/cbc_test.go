@@ -0,0 +1,130 @@
+package aes
+
+import (
+	"bytes"
+	gocipher "crypto/cipher"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SP 800-38A section F.2
+var cbcTests = []struct {
+	name       string
+	key        string
+	iv         string
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"F.2.1 CBC-AES128",
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		"000102030405060708090a0b0c0d0e0f",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b273bed6b8e3c1743b7116e69e222295163ff1caa1681fac09120eca307586e1a7",
+	},
+	{
+		"F.2.3 CBC-AES192",
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		"000102030405060708090a0b0c0d0e0f",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"4f021db243bc633d7178183a9fa071e8b4d9ada9ad7dedf4e5e738763f69145a571b242012fb7ae07fa9baac3df102e008b0e27988598881d920a9e64f5615cd",
+	},
+	{
+		"F.2.5 CBC-AES256",
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		"000102030405060708090a0b0c0d0e0f",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"f58c4c04d6e5f1ba779eabfb5f7bfbd69cfc4e967edb808d679f777bc6702c7d39f23369a9d9bacfa530e26304231461b2eb05e2c39be9fcda6c19078c6a9d1b",
+	},
+}
+
+func TestCBC(t *testing.T) {
+	for _, test := range cbcTests {
+		c, err := NewCipherBackend(decodeHex(t, test.key), TTable)
+		assert.NoError(t, err)
+
+		iv := decodeHex(t, test.iv)
+		plaintext := decodeHex(t, test.plaintext)
+		ciphertext := decodeHex(t, test.ciphertext)
+		out := make([]byte, len(plaintext))
+
+		enc, err := NewCBCEncrypter(c, iv)
+		assert.NoError(t, err)
+		enc.CryptBlocks(out, plaintext)
+		assert.Equal(t, ciphertext, out, test.name)
+
+		// decrypt in place, one block and then the other three, to check the
+		// chain carries over between calls
+		dec, err := NewCBCDecrypter(c, iv)
+		assert.NoError(t, err)
+		dec.CryptBlocks(out[:BlockSize], out[:BlockSize])
+		dec.CryptBlocks(out[BlockSize:], out[BlockSize:])
+		assert.Equal(t, plaintext, out, test.name)
+
+		// the same for encryption
+		enc, err = NewCBCEncrypter(c, iv)
+		assert.NoError(t, err)
+		copy(out, plaintext)
+		enc.CryptBlocks(out[:3*BlockSize], out[:3*BlockSize])
+		enc.CryptBlocks(out[3*BlockSize:], out[3*BlockSize:])
+		assert.Equal(t, ciphertext, out, test.name)
+	}
+}
+
+func TestCBCPadded(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, cbcTests[0].key))
+	assert.NoError(t, err)
+	iv, err := RandomIV()
+	assert.NoError(t, err)
+
+	for n := 0; n <= 3*BlockSize; n++ {
+		plaintext := bytes.Repeat([]byte{0x42}, n)
+
+		ciphertext, err := EncryptCBC(c, iv, plaintext, PKCS7{})
+		assert.NoError(t, err)
+
+		// the standard library agrees on the padded ciphertext
+		expected := PKCS7{}.Pad(plaintext, BlockSize)
+		gocipher.NewCBCEncrypter(c, iv).CryptBlocks(expected, expected)
+		assert.Equal(t, expected, ciphertext)
+
+		out, err := DecryptCBC(c, iv, ciphertext, PKCS7{})
+		assert.NoError(t, err)
+		assert.Equal(t, plaintext, out)
+	}
+
+	// unlike ECB, repeated plaintext blocks do not repeat in the ciphertext
+	ciphertext, err := EncryptCBC(c, iv, bytes.Repeat([]byte("sixteen byte blk"), 2), NoPadding{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, ciphertext[:BlockSize], ciphertext[BlockSize:])
+
+	_, err = DecryptCBC(c, iv, make([]byte, 20), PKCS7{})
+	assert.Equal(t, ErrNotFullBlocks, err)
+}
+
+func TestCBCBadIV(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	enc, err := NewCBCEncrypter(c, make([]byte, 8))
+	assert.Nil(t, enc)
+	assert.Equal(t, IVSizeError(8), err)
+
+	dec, err := NewCBCDecrypter(c, nil)
+	assert.Nil(t, dec)
+	assert.Equal(t, IVSizeError(0), err)
+
+	_, err = EncryptCBC(c, make([]byte, 17), []byte("hello"), PKCS7{})
+	assert.Equal(t, IVSizeError(17), err)
+}
+
+func TestRandomIV(t *testing.T) {
+	a, err := RandomIV()
+	assert.NoError(t, err)
+	b, err := RandomIV()
+	assert.NoError(t, err)
+
+	assert.Len(t, a, BlockSize)
+	assert.NotEqual(t, a, b)
+}
@@ -0,0 +1,136 @@
+package aes
+
+import gocipher "crypto/cipher"
+
+// CBC with ciphertext stealing (the SP 800-38A addendum) encrypts a message
+// of any length of at least one block without padding. The last partial
+// block is zero padded and encrypted as in CBC, and since that padding is
+// recoverable on decryption, the matching bytes at the end of the second to
+// last ciphertext block are left out. The ciphertext is exactly as long as
+// the plaintext. The three variants differ only in where the last two
+// ciphertext blocks go.
+
+// CSVariant selects the ordering of the last two ciphertext blocks
+type CSVariant int
+
+const (
+	// CS1 keeps the CBC order, so the partial block comes second to last
+	CS1 CSVariant = 1
+	// CS2 swaps the last two blocks when the last plaintext block is
+	// partial, so the partial block comes last, and is otherwise plain CBC
+	CS2 CSVariant = 2
+	// CS3 always swaps the last two blocks of a message longer than one
+	// block, as Kerberos (RFC 3962) does
+	CS3 CSVariant = 3
+)
+
+// EncryptCBCCS encrypts plaintext, which must be at least one block long, in
+// CBC mode from iv with ciphertext stealing variant v
+func EncryptCBCCS(b gocipher.Block, iv, plaintext []byte, v CSVariant) ([]byte, error) {
+	mode, err := NewCBCEncrypter(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCBCCS(b, plaintext, v); err != nil {
+		return nil, err
+	}
+
+	n := b.BlockSize()
+	partial := len(plaintext) % n
+
+	out := make([]byte, len(plaintext)+(n-partial)%n)
+	copy(out, plaintext)
+	mode.CryptBlocks(out, out)
+
+	if partial == 0 {
+		if v == CS3 {
+			swapCBCCS(out, n, n)
+		}
+		return out, nil
+	}
+
+	// drop the bytes of the second to last block that the padding of the
+	// last one will bring back
+	last := len(out) - n
+	copy(out[last-n+partial:], out[last:])
+	out = out[:len(plaintext)]
+
+	if v != CS1 {
+		swapCBCCS(out, partial, n)
+	}
+	return out, nil
+}
+
+// DecryptCBCCS decrypts ciphertext in CBC mode from iv with ciphertext
+// stealing variant v, which must be the one it was encrypted with
+func DecryptCBCCS(b gocipher.Block, iv, ciphertext []byte, v CSVariant) ([]byte, error) {
+	mode, err := NewCBCDecrypter(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCBCCS(b, ciphertext, v); err != nil {
+		return nil, err
+	}
+
+	n := b.BlockSize()
+	partial := len(ciphertext) % n
+
+	out := append([]byte(nil), ciphertext...)
+	if partial == 0 {
+		if v == CS3 {
+			swapCBCCS(out, n, n)
+		}
+		mode.CryptBlocks(out, out)
+		return out, nil
+	}
+
+	// back to the CS1 order, with the stolen block C_n-1* before C_n
+	if v != CS1 {
+		swapCBCCS(out, n, partial)
+	}
+
+	// decrypting C_n gives C_n-1 XOR the zero padded last block, so its
+	// tail is the tail C_n-1 lost, and C_n-1 can then be decrypted as usual
+	stolen := out[len(out)-n-partial : len(out)-n]
+	lastBlock := out[len(out)-n:]
+
+	z := make([]byte, n)
+	b.Decrypt(z, lastBlock)
+
+	prev := make([]byte, n)
+	copy(prev, stolen)
+	copy(prev[partial:], z[partial:])
+
+	last := make([]byte, partial)
+	xorBytes(last, z[:partial], stolen)
+
+	copy(out[len(out)-n-partial:], prev)
+	copy(out[len(out)-partial:], last)
+
+	mode.CryptBlocks(out[:len(out)-partial], out[:len(out)-partial])
+	return out, nil
+}
+
+func checkCBCCS(b gocipher.Block, in []byte, v CSVariant) error {
+	if v != CS1 && v != CS2 && v != CS3 {
+		return ErrCSVariant
+	}
+	if len(in) < b.BlockSize() {
+		return ErrShortInput
+	}
+	return nil
+}
+
+// swapCBCCS swaps the two pieces at the end of buf, one of length first
+// followed by one of length second, if buf holds both
+func swapCBCCS(buf []byte, first, second int) {
+	if len(buf) < first+second {
+		return
+	}
+
+	tail := buf[len(buf)-first-second:]
+	swapped := make([]byte, len(tail))
+	copy(swapped, tail[first:])
+	copy(swapped[second:], tail[:first])
+	copy(tail, swapped)
+}
@@ -0,0 +1,153 @@
+package aes
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cbcCSMessage is "I would like the General Gau's Chicken, please, and
+// wonton soup.", of which the RFC 3962 appendix B examples encrypt the first
+// 17, 31, 32, 47, 48 and 64 bytes under the key "chicken teriyaki" and a zero
+// IV
+const cbcCSMessage = "4920776f756c64206c696b65207468652047656e6572616c20476175277320436869636b656e2c20706c656173652c20616e6420776f6e746f6e20736f75702e"
+
+// RFC 3962's ciphertexts, which are CS3. There are no published CS1 or CS2
+// vectors, so those are checked against these converted by csFromCS3.
+var cbcCSTests = []struct {
+	length int
+	cs3    string
+}{
+	{
+		17,
+		"c6353568f2bf8cb4d8a580362da7ff7f97",
+	},
+	{
+		31,
+		"fc00783e0efdb2c1d445d4c8eff7ed2297687268d6ecccc0c07b25e25ecfe5",
+	},
+	{
+		32,
+		"39312523a78662d5be7fcbcc98ebf5a897687268d6ecccc0c07b25e25ecfe584",
+	},
+	{
+		47,
+		"97687268d6ecccc0c07b25e25ecfe584b3fffd940c16a18c1b5549d2f838029e39312523a78662d5be7fcbcc98ebf5",
+	},
+	{
+		48,
+		"97687268d6ecccc0c07b25e25ecfe5849dad8bbb96c4cdc03bc103e1a194bbd839312523a78662d5be7fcbcc98ebf5a8",
+	},
+	{
+		64,
+		"97687268d6ecccc0c07b25e25ecfe58439312523a78662d5be7fcbcc98ebf5a84807efe836ee89a526730dbc2f7bc8409dad8bbb96c4cdc03bc103e1a194bbd8",
+	},
+}
+
+// csFromCS3 reorders a CS3 ciphertext into variant v by the definitions of the
+// SP 800-38A addendum. CS3 ends in C_n followed by the possibly partial
+// C_n-1*; CS1 puts C_n-1* back before C_n, and CS2 is CS1 when C_n-1* is a
+// whole block and CS3 otherwise.
+func csFromCS3(cs3 []byte, v CSVariant) []byte {
+	if len(cs3) <= BlockSize || v == CS3 {
+		return cs3
+	}
+
+	partial := len(cs3) % BlockSize
+	if v == CS2 && partial != 0 {
+		return cs3
+	}
+	if partial == 0 {
+		partial = BlockSize
+	}
+
+	head := len(cs3) - BlockSize - partial
+	out := append([]byte(nil), cs3[:head]...)
+	out = append(out, cs3[head+BlockSize:]...)
+	return append(out, cs3[head:head+BlockSize]...)
+}
+
+func TestCBCCS(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, "636869636b656e207465726979616b69"))
+	assert.NoError(t, err)
+	iv := make([]byte, BlockSize)
+	message := decodeHex(t, cbcCSMessage)
+
+	for _, test := range cbcCSTests {
+		plaintext := message[:test.length]
+		cs3 := decodeHex(t, test.cs3)
+
+		for _, v := range []CSVariant{CS1, CS2, CS3} {
+			ciphertext := csFromCS3(cs3, v)
+
+			out, err := EncryptCBCCS(c, iv, plaintext, v)
+			assert.NoError(t, err)
+			assert.Equal(t, ciphertext, out, "CS%d length %d", v, test.length)
+
+			out, err = DecryptCBCCS(c, iv, ciphertext, v)
+			assert.NoError(t, err)
+			assert.Equal(t, plaintext, out, "CS%d length %d", v, test.length)
+		}
+	}
+}
+
+func TestCBCCSRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	key := make([]byte, 32)
+	iv := make([]byte, BlockSize)
+	rng.Read(key)
+	rng.Read(iv)
+	c, err := NewCipherBackend(key, TTable)
+	assert.NoError(t, err)
+
+	for n := 16; n <= 64; n++ {
+		plaintext := make([]byte, n)
+		rng.Read(plaintext)
+
+		// every variant is CBC up to the last two blocks
+		padded := make([]byte, (n+15)/16*16)
+		copy(padded, plaintext)
+		cbc, err := EncryptCBC(c, iv, padded, NoPadding{})
+		assert.NoError(t, err)
+
+		for _, v := range []CSVariant{CS1, CS2, CS3} {
+			ciphertext, err := EncryptCBCCS(c, iv, plaintext, v)
+			assert.NoError(t, err)
+			assert.Equal(t, n, len(ciphertext), "CS%d length %d", v, n)
+
+			tail := (n-1)%16 + 1 + 16
+			if n <= 16 {
+				tail = n
+			}
+			assert.Equal(t, cbc[:n-tail], ciphertext[:n-tail], "CS%d length %d", v, n)
+
+			decrypted, err := DecryptCBCCS(c, iv, ciphertext, v)
+			assert.NoError(t, err)
+			assert.Equal(t, plaintext, decrypted, "CS%d length %d", v, n)
+		}
+	}
+}
+
+func TestCBCCSErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+	iv := make([]byte, BlockSize)
+
+	for _, n := range []int{0, 1, 15} {
+		_, err = EncryptCBCCS(c, iv, make([]byte, n), CS3)
+		assert.Equal(t, ErrShortInput, err)
+		_, err = DecryptCBCCS(c, iv, make([]byte, n), CS3)
+		assert.Equal(t, ErrShortInput, err)
+	}
+
+	for _, v := range []CSVariant{0, 4} {
+		_, err = EncryptCBCCS(c, iv, make([]byte, 32), v)
+		assert.Equal(t, ErrCSVariant, err)
+		_, err = DecryptCBCCS(c, iv, make([]byte, 32), v)
+		assert.Equal(t, ErrCSVariant, err)
+	}
+
+	_, err = EncryptCBCCS(c, iv[:8], make([]byte, 32), CS1)
+	assert.Equal(t, IVSizeError(8), err)
+}
@@ -0,0 +1,226 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// Counter with CBC-MAC (SP 800-38C, RFC 3610) authenticates the additional
+// data and the plaintext with a CBC-MAC, then encrypts the plaintext and the
+// MAC in Counter mode. Everything is keyed with the same block cipher key.
+//
+// The nonce and the message length share the 15 bytes after the flags byte
+// of the first block, so a longer nonce means a shorter maximum message: an
+// n byte nonce leaves L = 15-n bytes for the length.
+
+const (
+	ccmMinNonceSize = 7
+	ccmMaxNonceSize = 13
+	ccmMinTagSize   = 4
+	ccmMaxTagSize   = 16
+)
+
+type ccm struct {
+	b         gocipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+var _ gocipher.AEAD = (*ccm)(nil)
+
+// NewCCM returns b wrapped in Counter with CBC-MAC mode. nonceSize must be
+// between 7 and 13 bytes and tagSize an even number of bytes between 4 and
+// 16. b must have a 16 byte block size.
+func NewCCM(b gocipher.Block, nonceSize, tagSize int) (gocipher.AEAD, error) {
+	if tagSize < ccmMinTagSize || tagSize > ccmMaxTagSize || tagSize%2 != 0 {
+		return nil, TagSizeError(tagSize)
+	}
+	return newCCM(b, nonceSize, tagSize)
+}
+
+// NewCCMStar returns b wrapped in CCM*, the variant of CCM used by IEEE
+// 802.15.4. It takes the same tag sizes as NewCCM and also a tagSize of 0,
+// which gives encryption only: nothing is authenticated and Open never
+// fails.
+func NewCCMStar(b gocipher.Block, nonceSize, tagSize int) (gocipher.AEAD, error) {
+	if tagSize == 0 {
+		return newCCM(b, nonceSize, 0)
+	}
+	return NewCCM(b, nonceSize, tagSize)
+}
+
+func newCCM(b gocipher.Block, nonceSize, tagSize int) (*ccm, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, BlockSizeError(b.BlockSize())
+	}
+	if nonceSize < ccmMinNonceSize || nonceSize > ccmMaxNonceSize {
+		return nil, IVSizeError(nonceSize)
+	}
+
+	return &ccm{b: b, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (c *ccm) NonceSize() int {
+	return c.nonceSize
+}
+
+func (c *ccm) Overhead() int {
+	return c.tagSize
+}
+
+// maxLength is the longest plaintext whose length fits in the L bytes left
+// over by the nonce
+func (c *ccm) maxLength() uint64 {
+	l := uint(BlockSize - 1 - c.nonceSize)
+	if l >= 8 {
+		return 1<<64 - 1
+	}
+	return 1<<(8*l) - 1
+}
+
+func (c *ccm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.nonceSize {
+		panic("aes: incorrect nonce length given to CCM")
+	}
+	if uint64(len(plaintext)) > c.maxLength() {
+		panic("aes: message too large for CCM")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+c.tagSize)
+
+	var tag [ccmMaxTagSize]byte
+	c.auth(tag[:], nonce, plaintext, additionalData)
+
+	stream, tagMask := c.start(nonce)
+	stream.XORKeyStream(out, plaintext)
+	xorBytes(out[len(plaintext):], tag[:c.tagSize], tagMask)
+
+	return ret
+}
+
+func (c *ccm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.nonceSize {
+		panic("aes: incorrect nonce length given to CCM")
+	}
+	if len(ciphertext) < c.tagSize || uint64(len(ciphertext)-c.tagSize) > c.maxLength() {
+		return nil, ErrAuthentication
+	}
+
+	tag := ciphertext[len(ciphertext)-c.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-c.tagSize]
+
+	// the MAC is over the plaintext, so decrypt first
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	stream, tagMask := c.start(nonce)
+	stream.XORKeyStream(out, ciphertext)
+
+	var expected [ccmMaxTagSize]byte
+	c.auth(expected[:], nonce, out, additionalData)
+	xorBytes(expected[:], expected[:c.tagSize], tagMask)
+
+	if subtle.ConstantTimeCompare(expected[:c.tagSize], tag) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, ErrAuthentication
+	}
+
+	return ret, nil
+}
+
+// start returns a Counter mode stream positioned at counter block A_1, along
+// with S_0, the encryption of A_0, for masking the tag
+func (c *ccm) start(nonce []byte) (*CTR, []byte) {
+	l := BlockSize - 1 - c.nonceSize
+
+	a0 := make([]byte, BlockSize)
+	a0[0] = byte(l - 1)
+	copy(a0[1:], nonce)
+
+	stream, err := NewCTR(c.b, a0, CounterWidth(8*l))
+	if err != nil {
+		panic(err)
+	}
+
+	tagMask := make([]byte, BlockSize)
+	stream.XORKeyStream(tagMask, tagMask)
+
+	return stream, tagMask
+}
+
+// auth writes the CBC-MAC of the formatted nonce, additional data and
+// plaintext to tag. It does nothing for a CCM* instance without a tag.
+func (c *ccm) auth(tag, nonce, plaintext, additionalData []byte) {
+	if c.tagSize == 0 {
+		return
+	}
+
+	l := BlockSize - 1 - c.nonceSize
+
+	// B_0 is the flags byte, the nonce and the plaintext length
+	var b0 [BlockSize]byte
+	b0[0] = byte((c.tagSize-2)/2<<3 | (l - 1))
+	if len(additionalData) > 0 {
+		b0[0] |= 0x40
+	}
+	copy(b0[1:], nonce)
+	var q [8]byte
+	binary.BigEndian.PutUint64(q[:], uint64(len(plaintext)))
+	copy(b0[1+c.nonceSize:], q[8-l:])
+
+	mac := cbcMAC{b: c.b, x: make([]byte, BlockSize)}
+	mac.update(b0[:])
+
+	// the additional data is prefixed with its length in a variable length
+	// encoding, and zero padded along with its prefix to a whole block
+	if n := uint64(len(additionalData)); n > 0 {
+		var prefix [10]byte
+		switch {
+		case n < 1<<16-1<<8:
+			binary.BigEndian.PutUint16(prefix[:], uint16(n))
+			mac.update(prefix[:2])
+		case n < 1<<32:
+			prefix[0], prefix[1] = 0xff, 0xfe
+			binary.BigEndian.PutUint32(prefix[2:], uint32(n))
+			mac.update(prefix[:6])
+		default:
+			prefix[0], prefix[1] = 0xff, 0xff
+			binary.BigEndian.PutUint64(prefix[2:], n)
+			mac.update(prefix[:10])
+		}
+		mac.update(additionalData)
+		mac.pad()
+	}
+
+	mac.update(plaintext)
+	mac.pad()
+
+	copy(tag, mac.x)
+}
+
+// cbcMAC is a CBC-MAC with a zero IV that takes its input in pieces
+type cbcMAC struct {
+	b gocipher.Block
+	x []byte
+	n int
+}
+
+func (m *cbcMAC) update(data []byte) {
+	for _, d := range data {
+		m.x[m.n] ^= d
+		m.n++
+		if m.n == len(m.x) {
+			m.b.Encrypt(m.x, m.x)
+			m.n = 0
+		}
+	}
+}
+
+// pad completes a partial block with zeros
+func (m *cbcMAC) pad() {
+	if m.n > 0 {
+		m.b.Encrypt(m.x, m.x)
+		m.n = 0
+	}
+}
@@ -0,0 +1,394 @@
+package aes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 3610 section 8
+var ccmTests = []struct {
+	name       string
+	key        string
+	nonce      string
+	ad         string
+	plaintext  string
+	tagSize    int
+	ciphertext string
+}{
+	{
+		"Packet Vector #1",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"00000003020100a0a1a2a3a4a5",
+		"0001020304050607",
+		"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e",
+		8,
+		"588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0",
+	},
+	{
+		"Packet Vector #2",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"00000004030201a0a1a2a3a4a5",
+		"0001020304050607",
+		"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		8,
+		"72c91a36e135f8cf291ca894085c87e3cc15c439c9e43a3ba091d56e10400916",
+	},
+	{
+		"Packet Vector #3",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"00000005040302a0a1a2a3a4a5",
+		"0001020304050607",
+		"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		8,
+		"51b1e5f44a197d1da46b0f8e2d282ae871e838bb64da8596574adaa76fbd9fb0c5",
+	},
+	{
+		"Packet Vector #4",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"00000006050403a0a1a2a3a4a5",
+		"000102030405060708090a0b",
+		"0c0d0e0f101112131415161718191a1b1c1d1e",
+		8,
+		"a28c6865939a9a79faaa5c4c2a9d4a91cdac8c96c861b9c9e61ef1",
+	},
+	{
+		"Packet Vector #5",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"00000007060504a0a1a2a3a4a5",
+		"000102030405060708090a0b",
+		"0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		8,
+		"dcf1fb7b5d9e23fb9d4e131253658ad86ebdca3e51e83f077d9c2d93",
+	},
+	{
+		"Packet Vector #6",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"00000008070605a0a1a2a3a4a5",
+		"000102030405060708090a0b",
+		"0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		8,
+		"6fc1b011f006568b5171a42d953d469b2570a4bd87405a0443ac91cb94",
+	},
+	{
+		"Packet Vector #7",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"00000009080706a0a1a2a3a4a5",
+		"0001020304050607",
+		"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e",
+		10,
+		"0135d1b2c95f41d5d1d4fec185d166b8094e999dfed96c048c56602c97acbb7490",
+	},
+	{
+		"Packet Vector #8",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"0000000a090807a0a1a2a3a4a5",
+		"0001020304050607",
+		"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		10,
+		"7b75399ac0831dd2f0bbd75879a2fd8f6cae6b6cd9b7db24c17b4433f434963f34b4",
+	},
+	{
+		"Packet Vector #9",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"0000000b0a0908a0a1a2a3a4a5",
+		"0001020304050607",
+		"08090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		10,
+		"82531a60cc24945a4b8279181ab5c84df21ce7f9b73f42e197ea9c07e56b5eb17e5f4e",
+	},
+	{
+		"Packet Vector #10",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"0000000c0b0a09a0a1a2a3a4a5",
+		"000102030405060708090a0b",
+		"0c0d0e0f101112131415161718191a1b1c1d1e",
+		10,
+		"07342594157785152b074098330abb141b947b566aa9406b4d999988dd",
+	},
+	{
+		"Packet Vector #11",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"0000000d0c0b0aa0a1a2a3a4a5",
+		"000102030405060708090a0b",
+		"0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		10,
+		"676bb20380b0e301e8ab79590a396da78b834934f53aa2e9107a8b6c022c",
+	},
+	{
+		"Packet Vector #12",
+		"c0c1c2c3c4c5c6c7c8c9cacbcccdcecf",
+		"0000000e0d0c0ba0a1a2a3a4a5",
+		"000102030405060708090a0b",
+		"0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		10,
+		"c0ffa0d6f05bdb67f24d43a4338d2aa4bed7b20e43cd1aa31662e7ad65d6db",
+	},
+	{
+		"Packet Vector #13",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"00412b4ea9cdbe3c9696766cfa",
+		"0be1a88bace018b1",
+		"08e8cf97d820ea258460e96ad9cf5289054d895ceac47c",
+		8,
+		"4cb97f86a2a4689a877947ab8091ef5386a6ffbdd080f8e78cf7cb0cddd7b3",
+	},
+	{
+		"Packet Vector #14",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"0033568ef7b2633c9696766cfa",
+		"63018f76dc8a1bcb",
+		"9020ea6f91bdd85afa0039ba4baff9bfb79c7028949cd0ec",
+		8,
+		"4ccb1e7ca981befaa0726c55d378061298c85c92814abc33c52ee81d7d77c08a",
+	},
+	{
+		"Packet Vector #15",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"00103fe41336713c9696766cfa",
+		"aa6cfa36cae86b40",
+		"b916e0eacc1c00d7dcec68ec0b3bbb1a02de8a2d1aa346132e",
+		8,
+		"b1d23a2220ddc0ac900d9aa03c61fcf4a559a4417767089708a776796edb723506",
+	},
+	{
+		"Packet Vector #16",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"00764c63b8058e3c9696766cfa",
+		"d0d0735c531e1becf049c244",
+		"12daac5630efa5396f770ce1a66b21f7b2101c",
+		8,
+		"14d253c3967b70609b7cbb7c499160283245269a6f49975bcadeaf",
+	},
+	{
+		"Packet Vector #17",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"00f8b678094e3b3c9696766cfa",
+		"77b60f011c03e1525899bcae",
+		"e88b6a46c78d63e52eb8c546efb5de6f75e9cc0d",
+		8,
+		"5545ff1a085ee2efbf52b2e04bee1e2336c73e3f762c0c7744fe7e3c",
+	},
+	{
+		"Packet Vector #18",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"00d560912d3f703c9696766cfa",
+		"cd9044d2b71fdb8120ea60c0",
+		"6435acbafb11a82e2f071d7ca4a5ebd93a803ba87f",
+		8,
+		"009769ecabdf48625594c59251e6035722675e04c847099e5ae0704551",
+	},
+	{
+		"Packet Vector #19",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"0042fff8f1951c3c9696766cfa",
+		"d85bc7e69f944fb8",
+		"8a19b950bcf71a018e5e6701c91787659809d67dbedd18",
+		10,
+		"bc218daa947427b6db386a99ac1aef23ade0b52939cb6a637cf9bec2408897c6ba",
+	},
+	{
+		"Packet Vector #20",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"00920f40e56cdc3c9696766cfa",
+		"74a0ebc9069f5b37",
+		"1761433c37c5a35fc1f39f406302eb907c6163be38c98437",
+		10,
+		"5810e6fd25874022e80361a478e3e9cf484ab04f447efff6f0a477cc2fc9bf548944",
+	},
+	{
+		"Packet Vector #21",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"0027ca0c7120bc3c9696766cfa",
+		"44a3aa3aae6475ca",
+		"a434a8e58500c6e41530538862d686ea9e81301b5ae4226bfa",
+		10,
+		"f2beed7bc5098e83feb5b31608f8e29c38819a89c8e776f1544d4151a4ed3a8b87b9ce",
+	},
+	{
+		"Packet Vector #22",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"005b8ccbcd9af83c9696766cfa",
+		"ec46bb63b02520c33c49fd70",
+		"b96b49e21d621741632875db7f6c9243d2d7c2",
+		10,
+		"31d750a09da3ed7fddd49a2032aabf17ec8ebf7d22c8088c666be5c197",
+	},
+	{
+		"Packet Vector #23",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"003ebe94044b9a3c9696766cfa",
+		"47a65ac78b3d594227e85e71",
+		"e2fcfbb880442c731bf95167c8ffd7895e337076",
+		10,
+		"e882f1dbd38ce3eda7c23f04dd65071eb41342acdf7e00dccec7ae52987d",
+	},
+	{
+		"Packet Vector #24",
+		"d7828d13b2b0bdc325a76236df93cc6b",
+		"008d493b30ae8b3c9696766cfa",
+		"6e37a6ef546d955d34ab6059",
+		"abf21c0b02feb88f856df4a37381bce3cc128517d4",
+		10,
+		"f32905b88a641b04b9c9ffb58cc390900f3da12ab16dce9e82efa16da62059",
+	},
+}
+
+func TestCCM(t *testing.T) {
+	for _, test := range ccmTests {
+		c, err := NewCipher(decodeHex(t, test.key))
+		assert.NoError(t, err)
+
+		nonce := decodeHex(t, test.nonce)
+		ad := decodeHex(t, test.ad)
+		plaintext := decodeHex(t, test.plaintext)
+		ciphertext := decodeHex(t, test.ciphertext)
+
+		aead, err := NewCCM(c, len(nonce), test.tagSize)
+		assert.NoError(t, err)
+
+		assert.Equal(t, ciphertext, aead.Seal(nil, nonce, plaintext, ad), test.name)
+
+		opened, err := aead.Open(nil, nonce, ciphertext, ad)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, plaintext, opened, test.name)
+	}
+}
+
+// ccmPattern returns n bytes of the pattern used for the additional data of
+// ccmSizeTests
+func ccmPattern(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// The sizes RFC 3610 does not cover, checked against OpenSSL. The key is
+// 000102...0f, the nonce the first nonceSize bytes of 101112...1c, the
+// additional data ccmPattern and plaintext byte i is i^0x5a. The two long
+// additional data lengths straddle the switch to the six byte length prefix.
+var ccmSizeTests = []struct {
+	nonceSize  int
+	tagSize    int
+	adLen      int
+	ptLen      int
+	ciphertext string
+}{
+	{7, 4, 65280, 20, "f035650bcbb6ad1ab450a79f4bb9a11531f33d4ff97dcc3b"},
+	{13, 16, 65279, 20, "26ba2818e603b289e90a18c0795038d460981d30925be2b01d8a2f10d49da76fb45376ac"},
+	{7, 16, 0, 0, "12353999ba3c90ae6a46487d07d8bd23"},
+	{12, 14, 3, 40, "79eee1f91cab71ef58ff2f9122338fd331fc9afe7b084666b4abe711956229c12768d4139de343ce1abd57576f359e29328325e90252"},
+}
+
+func TestCCMSizes(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	assert.NoError(t, err)
+
+	for _, test := range ccmSizeTests {
+		nonce := decodeHex(t, "101112131415161718191a1b1c")[:test.nonceSize]
+		ad := ccmPattern(test.adLen)
+		plaintext := make([]byte, test.ptLen)
+		for i := range plaintext {
+			plaintext[i] = byte(i) ^ 0x5a
+		}
+
+		aead, err := NewCCM(c, test.nonceSize, test.tagSize)
+		assert.NoError(t, err)
+
+		sealed := aead.Seal(nil, nonce, plaintext, ad)
+		assert.Equal(t, decodeHex(t, test.ciphertext), sealed, "nonce %d tag %d ad %d", test.nonceSize, test.tagSize, test.adLen)
+
+		opened, err := aead.Open(nil, nonce, sealed, ad)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(plaintext, opened))
+	}
+}
+
+func TestCCMStar(t *testing.T) {
+	test := ccmTests[0]
+	c, err := NewCipher(decodeHex(t, test.key))
+	assert.NoError(t, err)
+
+	nonce := decodeHex(t, test.nonce)
+	ad := decodeHex(t, test.ad)
+	plaintext := decodeHex(t, test.plaintext)
+	ciphertext := decodeHex(t, test.ciphertext)
+
+	// with a tag CCM* is plain CCM
+	aead, err := NewCCMStar(c, len(nonce), test.tagSize)
+	assert.NoError(t, err)
+	assert.Equal(t, ciphertext, aead.Seal(nil, nonce, plaintext, ad))
+
+	// without one it is the same Counter mode encryption and nothing more
+	aead, err = NewCCMStar(c, len(nonce), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, aead.Overhead())
+
+	sealed := aead.Seal(nil, nonce, plaintext, ad)
+	assert.Equal(t, ciphertext[:len(plaintext)], sealed)
+
+	opened, err := aead.Open(nil, nonce, sealed, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	_, err = NewCCM(c, len(nonce), 0)
+	assert.Equal(t, TagSizeError(0), err)
+}
+
+func TestCCMTampering(t *testing.T) {
+	test := ccmTests[0]
+	c, err := NewCipher(decodeHex(t, test.key))
+	assert.NoError(t, err)
+	aead, err := NewCCM(c, 13, test.tagSize)
+	assert.NoError(t, err)
+
+	nonce := decodeHex(t, test.nonce)
+	ad := decodeHex(t, test.ad)
+	sealed := decodeHex(t, test.ciphertext)
+
+	for i := range sealed {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x01
+
+		// decrypted output is wiped rather than left behind in dst
+		dst := make([]byte, 0, len(sealed))
+		opened, err := aead.Open(dst, nonce, tampered, ad)
+		assert.Equal(t, ErrAuthentication, err, "byte %d", i)
+		assert.Nil(t, opened)
+		assert.Equal(t, make([]byte, len(sealed)-test.tagSize), dst[:len(sealed)-test.tagSize])
+	}
+
+	badAD := append([]byte(nil), ad...)
+	badAD[0] ^= 0x01
+	_, err = aead.Open(nil, nonce, sealed, badAD)
+	assert.Equal(t, ErrAuthentication, err)
+
+	_, err = aead.Open(nil, nonce, sealed[:3], ad)
+	assert.Equal(t, ErrAuthentication, err)
+}
+
+func TestCCMErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	for _, nonceSize := range []int{0, 6, 14, 16} {
+		_, err = NewCCM(c, nonceSize, 8)
+		assert.Equal(t, IVSizeError(nonceSize), err)
+	}
+	for _, tagSize := range []int{2, 3, 5, 15, 18} {
+		_, err = NewCCM(c, 12, tagSize)
+		assert.Equal(t, TagSizeError(tagSize), err)
+		_, err = NewCCMStar(c, 12, tagSize)
+		assert.Equal(t, TagSizeError(tagSize), err)
+	}
+
+	aead, err := NewCCM(c, 13, 8)
+	assert.NoError(t, err)
+	assert.Panics(t, func() { aead.Seal(nil, make([]byte, 12), nil, nil) })
+
+	// a 13 byte nonce leaves two bytes for the length
+	assert.Panics(t, func() { aead.Seal(nil, make([]byte, 13), make([]byte, 1<<16), nil) })
+}
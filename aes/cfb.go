@@ -0,0 +1,132 @@
+package aes
+
+import gocipher "crypto/cipher"
+
+// Cipher Feedback mode (SP 800-38A section 6.3) encrypts a shift register,
+// initially the IV, and XORs the leading s bits of the result with the next s
+// bit segment of the message. The ciphertext segment is then shifted into the
+// register, so each segment of keystream depends on the ciphertext before it.
+
+// SegmentSize is the number of bits of message that each block encryption
+// handles in Cipher Feedback mode
+type SegmentSize int
+
+const (
+	// CFB1 encrypts one bit per block encryption, working from the most
+	// significant bit of each byte
+	CFB1 SegmentSize = 1
+	// CFB8 encrypts one byte per block encryption
+	CFB8 SegmentSize = 8
+	// CFB128 encrypts a whole block per block encryption, the CFB of the Go
+	// standard library
+	CFB128 SegmentSize = 128
+)
+
+type cfb struct {
+	b       gocipher.Block
+	decrypt bool
+
+	// next collects the ciphertext of the current segment and used counts
+	// its bytes. CFB1 shifts each bit straight into the register instead.
+	segment  SegmentSize
+	register []byte
+	ks       []byte
+	next     []byte
+	used     int
+}
+
+// NewCFBEncrypter returns a crypto/cipher.Stream which encrypts in Cipher
+// Feedback mode with segment bit segments, starting from iv. iv must be one
+// block long, otherwise an IVSizeError is returned, and segment must be 1 or a
+// multiple of 8 up to the block size, otherwise ErrSegmentSize is returned.
+func NewCFBEncrypter(b gocipher.Block, iv []byte, segment SegmentSize) (gocipher.Stream, error) {
+	return newCFB(b, iv, segment, false)
+}
+
+// NewCFBDecrypter is the decrypting counterpart of NewCFBEncrypter
+func NewCFBDecrypter(b gocipher.Block, iv []byte, segment SegmentSize) (gocipher.Stream, error) {
+	return newCFB(b, iv, segment, true)
+}
+
+func newCFB(b gocipher.Block, iv []byte, segment SegmentSize, decrypt bool) (*cfb, error) {
+	if len(iv) != b.BlockSize() {
+		return nil, IVSizeError(len(iv))
+	}
+	if segment != CFB1 && (segment < 8 || int(segment) > 8*b.BlockSize() || segment%8 != 0) {
+		return nil, ErrSegmentSize
+	}
+
+	x := &cfb{
+		b:        b,
+		decrypt:  decrypt,
+		segment:  segment,
+		register: append([]byte(nil), iv...),
+		ks:       make([]byte, len(iv)),
+		next:     make([]byte, len(iv)),
+	}
+	return x, nil
+}
+
+func (x *cfb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("aes: output smaller than input")
+	}
+
+	if x.segment == CFB1 {
+		for i, in := range src {
+			dst[i] = x.cryptByteBits(in)
+		}
+		return
+	}
+
+	n := int(x.segment) / 8
+	for i, in := range src {
+		if x.used == 0 {
+			x.b.Encrypt(x.ks, x.register)
+		}
+
+		out := in ^ x.ks[x.used]
+		if x.decrypt {
+			x.next[x.used] = in
+		} else {
+			x.next[x.used] = out
+		}
+		dst[i] = out
+		x.used++
+
+		// shift the finished ciphertext segment into the register
+		if x.used == n {
+			copy(x.register, x.register[n:])
+			copy(x.register[len(x.register)-n:], x.next[:n])
+			x.used = 0
+		}
+	}
+}
+
+// cryptByteBits runs CFB1 over the eight bits of in, most significant first
+func (x *cfb) cryptByteBits(in byte) byte {
+	var out byte
+	for bit := 7; bit >= 0; bit-- {
+		x.b.Encrypt(x.ks, x.register)
+
+		p := in >> uint(bit) & 1
+		c := p ^ x.ks[0]>>7
+		out |= c << uint(bit)
+
+		feedback := c
+		if x.decrypt {
+			feedback = p
+		}
+		shiftLeftBit(x.register, feedback)
+	}
+	return out
+}
+
+// shiftLeftBit shifts b left by one bit as a big-endian integer, shifting bit
+// in at the bottom
+func shiftLeftBit(b []byte, bit byte) {
+	for i := 0; i < len(b)-1; i++ {
+		b[i] = b[i]<<1 | b[i+1]>>7
+	}
+	b[len(b)-1] = b[len(b)-1]<<1 | bit
+}
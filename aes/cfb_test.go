@@ -0,0 +1,176 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SP 800-38A section F.3. The CFB1 vectors are the 16 bits of the example
+// packed into two bytes, most significant bit first.
+var cfbTests = []struct {
+	name       string
+	key        string
+	segment    SegmentSize
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"F.3.1 CFB1-AES128",
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		CFB1,
+		"6bc1",
+		"68b3",
+	},
+	{
+		"F.3.3 CFB1-AES192",
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		CFB1,
+		"6bc1",
+		"9359",
+	},
+	{
+		"F.3.5 CFB1-AES256",
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		CFB1,
+		"6bc1",
+		"9029",
+	},
+	{
+		"F.3.7 CFB8-AES128",
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		CFB8,
+		"6bc1bee22e409f96e93d7e117393172aae2d",
+		"3b79424c9c0dd436bace9e0ed4586a4f32b9",
+	},
+	{
+		"F.3.9 CFB8-AES192",
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		CFB8,
+		"6bc1bee22e409f96e93d7e117393172aae2d",
+		"cda2521ef0a905ca44cd057cbf0d47a0678a",
+	},
+	{
+		"F.3.11 CFB8-AES256",
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		CFB8,
+		"6bc1bee22e409f96e93d7e117393172aae2d",
+		"dc1f1a8520a64db55fcc8ac554844e889700",
+	},
+	{
+		"F.3.13 CFB128-AES128",
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		CFB128,
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"3b3fd92eb72dad20333449f8e83cfb4ac8a64537a0b3a93fcde3cdad9f1ce58b26751f67a3cbb140b1808cf187a4f4dfc04b05357c5d1c0eeac4c66f9ff7f2e6",
+	},
+	{
+		"F.3.15 CFB128-AES192",
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		CFB128,
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"cdc80d6fddf18cab34c25909c99a417467ce7f7f81173621961a2b70171d3d7a2e1e8a1dd59b88b1c8e60fed1efac4c9c05f9f9ca9834fa042ae8fba584b09ff",
+	},
+	{
+		"F.3.17 CFB128-AES256",
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		CFB128,
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"dc7e84bfda79164b7ecd8486985d386039ffed143b28b1c832113c6331e5407bdf10132415e54b92a13ed0a8267ae2f975a385741ab9cef82031623d55b1e471",
+	},
+}
+
+func TestCFB(t *testing.T) {
+	iv := decodeHex(t, "000102030405060708090a0b0c0d0e0f")
+
+	for _, test := range cfbTests {
+		c, err := NewCipherBackend(decodeHex(t, test.key), TTable)
+		assert.NoError(t, err)
+
+		plaintext := decodeHex(t, test.plaintext)
+		ciphertext := decodeHex(t, test.ciphertext)
+
+		// a byte at a time and all at once
+		stream, err := NewCFBEncrypter(c, iv, test.segment)
+		assert.NoError(t, err)
+		out := make([]byte, len(plaintext))
+		for i := range plaintext {
+			stream.XORKeyStream(out[i:i+1], plaintext[i:i+1])
+		}
+		assert.Equal(t, ciphertext, out, test.name)
+
+		stream, err = NewCFBEncrypter(c, iv, test.segment)
+		assert.NoError(t, err)
+		stream.XORKeyStream(out, plaintext)
+		assert.Equal(t, ciphertext, out, test.name)
+
+		// and decrypt in place
+		stream, err = NewCFBDecrypter(c, iv, test.segment)
+		assert.NoError(t, err)
+		stream.XORKeyStream(out, out)
+		assert.Equal(t, plaintext, out, test.name)
+	}
+}
+
+func TestCFBStdlib(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, cfbTests[0].key))
+	assert.NoError(t, err)
+	iv := decodeHex(t, "000102030405060708090a0b0c0d0e0f")
+
+	rng := rand.New(rand.NewSource(465))
+	plaintext := make([]byte, 1000)
+	rng.Read(plaintext)
+
+	expected := make([]byte, len(plaintext))
+	gocipher.NewCFBEncrypter(c, iv).XORKeyStream(expected, plaintext)
+
+	stream, err := NewCFBEncrypter(c, iv, CFB128)
+	assert.NoError(t, err)
+	out := make([]byte, len(plaintext))
+	for _, piece := range [][2]int{{0, 7}, {7, 16}, {16, 500}, {500, 1000}} {
+		stream.XORKeyStream(out[piece[0]:piece[1]], plaintext[piece[0]:piece[1]])
+	}
+	assert.Equal(t, expected, out)
+}
+
+func TestCFBRoundTrip(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, cfbTests[0].key))
+	assert.NoError(t, err)
+	iv := decodeHex(t, "000102030405060708090a0b0c0d0e0f")
+
+	rng := rand.New(rand.NewSource(465))
+	plaintext := make([]byte, 50)
+	rng.Read(plaintext)
+
+	// every segment size the mode allows, not just the three SP 800-38A lists
+	for _, segment := range []SegmentSize{1, 8, 16, 64, 120, 128} {
+		encrypter, err := NewCFBEncrypter(c, iv, segment)
+		assert.NoError(t, err)
+		ciphertext := make([]byte, len(plaintext))
+		encrypter.XORKeyStream(ciphertext, plaintext)
+
+		decrypter, err := NewCFBDecrypter(c, iv, segment)
+		assert.NoError(t, err)
+		out := make([]byte, len(plaintext))
+		decrypter.XORKeyStream(out[:13], ciphertext[:13])
+		decrypter.XORKeyStream(out[13:], ciphertext[13:])
+		assert.Equal(t, plaintext, out, "segment %d", segment)
+	}
+}
+
+func TestCFBErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	_, err = NewCFBEncrypter(c, make([]byte, 8), CFB8)
+	assert.Equal(t, IVSizeError(8), err)
+
+	for _, segment := range []SegmentSize{0, 2, 7, 12, 136} {
+		_, err = NewCFBEncrypter(c, make([]byte, 16), segment)
+		assert.Equal(t, ErrSegmentSize, err, "segment %d", segment)
+		_, err = NewCFBDecrypter(c, make([]byte, 16), segment)
+		assert.Equal(t, ErrSegmentSize, err, "segment %d", segment)
+	}
+}
@@ -0,0 +1,124 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"hash"
+)
+
+// CMAC (SP 800-38B, RFC 4493) is a CBC-MAC whose last block is masked with
+// one of two subkeys derived from the encryption of the zero block: K1 when
+// the message fills its last block and K2 when the last block had to be
+// padded. The masking is what makes it safe for messages of varying length.
+
+// cmacMinTagSize is the shortest tag NewCMACWithTagSize accepts. SP 800-38B
+// appendix A advises at least 8 bytes unless the number of forgery attempts
+// is limited, as it is for LoRaWAN's 4 byte MIC.
+const cmacMinTagSize = 4
+
+// CMAC is a CMAC computation in progress. It implements hash.Hash, and the
+// sum it appends is the leading Size bytes of the full 16 byte tag.
+type CMAC struct {
+	b       gocipher.Block
+	k1, k2  []byte
+	tagSize int
+
+	// x is the CBC-MAC state over every block before the one in buf, which
+	// is held back until more data shows it is not the last
+	x   [BlockSize]byte
+	buf [BlockSize]byte
+	n   int
+}
+
+var _ hash.Hash = (*CMAC)(nil)
+
+// NewCMAC returns a CMAC under b with full 16 byte tags. b must have a 16
+// byte block size.
+func NewCMAC(b gocipher.Block) (*CMAC, error) {
+	return NewCMACWithTagSize(b, BlockSize)
+}
+
+// NewCMACWithTagSize returns a CMAC under b with tags truncated to tagSize
+// bytes, between 4 and 16
+func NewCMACWithTagSize(b gocipher.Block, tagSize int) (*CMAC, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, BlockSizeError(b.BlockSize())
+	}
+	if tagSize < cmacMinTagSize || tagSize > BlockSize {
+		return nil, TagSizeError(tagSize)
+	}
+
+	k1, k2 := cmacSubkeys(b)
+	return &CMAC{b: b, k1: k1, k2: k2, tagSize: tagSize}, nil
+}
+
+// Write adds p to the message. It never returns an error.
+func (c *CMAC) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if c.n == BlockSize {
+			xorBytes(c.x[:], c.x[:], c.buf[:])
+			c.b.Encrypt(c.x[:], c.x[:])
+			c.n = 0
+		}
+
+		n := copy(c.buf[c.n:], p)
+		c.n += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Sum appends the tag of the message written so far to b. It does not change
+// the state, so more of the message may be written afterwards.
+func (c *CMAC) Sum(b []byte) []byte {
+	var mac [BlockSize]byte
+	c.sum(mac[:])
+	return append(b, mac[:c.tagSize]...)
+}
+
+// Reset starts a new message under the same key
+func (c *CMAC) Reset() {
+	c.x = [BlockSize]byte{}
+	c.n = 0
+}
+
+// Size returns the tag size
+func (c *CMAC) Size() int {
+	return c.tagSize
+}
+
+// BlockSize returns the block size of the cipher, 16 bytes
+func (c *CMAC) BlockSize() int {
+	return BlockSize
+}
+
+// sum writes the full 16 byte tag to mac
+func (c *CMAC) sum(mac []byte) {
+	x := c.x
+	if c.n == BlockSize {
+		xorBytes(x[:], x[:], c.k1)
+	} else {
+		xorBytes(x[:], x[:], c.k2)
+		x[c.n] ^= 0x80
+	}
+	xorBytes(x[:], x[:], c.buf[:c.n])
+	c.b.Encrypt(mac, x[:])
+}
+
+// cmacSubkeys derives K1 and K2 for b
+func cmacSubkeys(b gocipher.Block) (k1, k2 []byte) {
+	k1 = make([]byte, BlockSize)
+	b.Encrypt(k1, k1)
+	double(k1)
+
+	k2 = append([]byte(nil), k1...)
+	double(k2)
+	return k1, k2
+}
+
+// cmacSum writes the CMAC of data under b, with subkeys k1 and k2, to mac
+func cmacSum(mac []byte, b gocipher.Block, k1, k2, data []byte) {
+	c := CMAC{b: b, k1: k1, k2: k2, tagSize: BlockSize}
+	c.Write(data)
+	c.sum(mac)
+}
@@ -0,0 +1,141 @@
+package aes
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// cmacMessage is the message of RFC 4493 section 4 and SP 800-38B appendix
+// D, of which the examples take the first 0, 16, 40 and 64 bytes
+const cmacMessage = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+// RFC 4493 section 4 for the 128 bit key, and SP 800-38B appendix D.2 and
+// D.3 for the 192 and 256 bit keys
+var cmacTests = []struct {
+	key  string
+	k1   string
+	k2   string
+	tags [4]string
+}{
+	{
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		"fbeed618357133667c85e08f7236a8de",
+		"f7ddac306ae266ccf90bc11ee46d513b",
+		[4]string{
+			"bb1d6929e95937287fa37d129b756746",
+			"070a16b46b4d4144f79bdd9dd04a287c",
+			"dfa66747de9ae63030ca32611497c827",
+			"51f0bebf7e3b9d92fc49741779363cfe",
+		},
+	},
+	{
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		"448a5b1c93514b273ee6439dd4daa296",
+		"8914b63926a2964e7dcc873ba9b5452c",
+		[4]string{
+			"d17ddf46adaacde531cac483de7a9367",
+			"9e99a7bf31e710900662f65e617c5184",
+			"8a1de5be2eb31aad089a82e6ee908b0e",
+			"a1d5df0eed790f794d77589659f39a11",
+		},
+	},
+	{
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		"cad1ed03299eedac2e9a99808621502f",
+		"95a3da06533ddb585d3533010c42a0d9",
+		[4]string{
+			"028962f61b7bf89efc6b551f4667d983",
+			"28a7023f452e8f82bd4bf28d8c37c35c",
+			"aaf3d8f1de5640c232f5b169b9c911e6",
+			"e1992190549f6ed5696a2c056c315410",
+		},
+	},
+}
+
+var cmacLengths = [4]int{0, 16, 40, 64}
+
+func TestCMAC(t *testing.T) {
+	message := decodeHex(t, cmacMessage)
+
+	for _, test := range cmacTests {
+		c, err := NewCipher(decodeHex(t, test.key))
+		assert.NoError(t, err)
+
+		k1, k2 := cmacSubkeys(c)
+		assert.Equal(t, decodeHex(t, test.k1), k1, test.key)
+		assert.Equal(t, decodeHex(t, test.k2), k2, test.key)
+
+		mac, err := NewCMAC(c)
+		assert.NoError(t, err)
+		assert.Equal(t, 16, mac.Size())
+		assert.Equal(t, 16, mac.BlockSize())
+
+		for i, n := range cmacLengths {
+			mac.Reset()
+			mac.Write(message[:n])
+			assert.Equal(t, decodeHex(t, test.tags[i]), mac.Sum(nil), "%s length %d", test.key, n)
+		}
+	}
+}
+
+func TestCMACTagSize(t *testing.T) {
+	test := cmacTests[0]
+	c, err := NewCipher(decodeHex(t, test.key))
+	assert.NoError(t, err)
+	message := decodeHex(t, cmacMessage)
+
+	for tagSize := 4; tagSize <= 16; tagSize++ {
+		mac, err := NewCMACWithTagSize(c, tagSize)
+		assert.NoError(t, err)
+		assert.Equal(t, tagSize, mac.Size())
+
+		mac.Write(message[:40])
+		assert.Equal(t, decodeHex(t, test.tags[2])[:tagSize], mac.Sum(nil), "tag size %d", tagSize)
+	}
+}
+
+func TestCMACWrites(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	key := make([]byte, 16)
+	rng.Read(key)
+	c, err := NewCipherBackend(key, TTable)
+	assert.NoError(t, err)
+
+	mac, err := NewCMAC(c)
+	assert.NoError(t, err)
+
+	message := make([]byte, 100)
+	rng.Read(message)
+
+	for n := 0; n <= len(message); n++ {
+		mac.Reset()
+		mac.Write(message[:n])
+		expected := mac.Sum(nil)
+
+		// the message split in random pieces, with a Sum partway through
+		mac.Reset()
+		for rest := message[:n]; len(rest) > 0; {
+			k := rng.Intn(len(rest) + 1)
+			mac.Write(rest[:k])
+			mac.Sum(nil)
+			rest = rest[k:]
+		}
+		assert.Equal(t, expected, mac.Sum([]byte{}), "length %d", n)
+
+		var sum [BlockSize]byte
+		cmacSum(sum[:], c, mac.k1, mac.k2, message[:n])
+		assert.Equal(t, expected, sum[:], "length %d", n)
+	}
+}
+
+func TestCMACErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	for _, tagSize := range []int{-1, 0, 3, 17} {
+		_, err = NewCMACWithTagSize(c, tagSize)
+		assert.Equal(t, TagSizeError(tagSize), err)
+	}
+}
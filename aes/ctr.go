@@ -0,0 +1,117 @@
+package aes
+
+import gocipher "crypto/cipher"
+
+// Counter mode (SP 800-38A section 6.5) turns the block cipher into a stream
+// cipher by encrypting successive counter blocks and XORing the result with
+// the message. Block i of the keystream depends only on the initial counter
+// block and i, so the stream can be started at any offset.
+
+// CounterWidth is the number of low order bits of the counter block that are
+// incremented, as a big-endian integer, from one block to the next. The
+// remaining high order bits never change.
+type CounterWidth int
+
+const (
+	// Counter128 increments the whole counter block
+	Counter128 CounterWidth = 128
+	// Counter32 increments only the last four bytes, leaving the first twelve
+	// as a fixed nonce, as GCM and many protocols do
+	Counter32 CounterWidth = 32
+)
+
+// CTR is a Counter mode keystream. It implements crypto/cipher.Stream.
+type CTR struct {
+	b     gocipher.Block
+	width int
+	iv    []byte
+	ctr   []byte
+	ks    []byte
+	used  int
+}
+
+var _ gocipher.Stream = (*CTR)(nil)
+
+// NewCTR returns a Counter mode stream whose first counter block is iv. iv
+// must be one block long, otherwise an IVSizeError is returned, and width must
+// be a whole number of bytes no wider than the block, otherwise
+// ErrCounterWidth is returned.
+func NewCTR(b gocipher.Block, iv []byte, width CounterWidth) (*CTR, error) {
+	if len(iv) != b.BlockSize() {
+		return nil, IVSizeError(len(iv))
+	}
+	if width < 8 || int(width) > 8*b.BlockSize() || width%8 != 0 {
+		return nil, ErrCounterWidth
+	}
+
+	x := &CTR{
+		b:     b,
+		width: int(width) / 8,
+		iv:    append([]byte(nil), iv...),
+		ctr:   make([]byte, len(iv)),
+		ks:    make([]byte, len(iv)),
+	}
+	x.Seek(0)
+	return x, nil
+}
+
+// XORKeyStream XORs each byte of src with the next byte of the keystream and
+// writes the result to dst, which must be at least as long as src
+func (x *CTR) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("aes: output smaller than input")
+	}
+
+	for len(src) > 0 {
+		if x.used == len(x.ks) {
+			x.increment()
+			x.b.Encrypt(x.ks, x.ctr)
+			x.used = 0
+		}
+
+		n := xorBytes(dst, src, x.ks[x.used:])
+		x.used += n
+		src = src[n:]
+		dst = dst[n:]
+	}
+}
+
+// Seek moves the stream to offset bytes from its start, so that the next call
+// to XORKeyStream processes message byte offset. This is how the middle of a
+// large message is decrypted without processing what comes before.
+func (x *CTR) Seek(offset uint64) {
+	blockSize := uint64(len(x.iv))
+
+	copy(x.ctr, x.iv)
+	addCounter(x.ctr[len(x.ctr)-x.width:], offset/blockSize)
+
+	x.b.Encrypt(x.ks, x.ctr)
+	x.used = int(offset % blockSize)
+}
+
+func (x *CTR) increment() {
+	addCounter(x.ctr[len(x.ctr)-x.width:], 1)
+}
+
+// addCounter adds n to the big-endian integer ctr, discarding any carry out of
+// its top byte so that the counter wraps around
+func addCounter(ctr []byte, n uint64) {
+	for i := len(ctr) - 1; i >= 0 && n != 0; i-- {
+		sum := uint64(ctr[i]) + n&0xff
+		ctr[i] = byte(sum)
+		n = n>>8 + sum>>8
+	}
+}
+
+// xorBytes sets dst[i] = a[i] ^ b[i] for as many bytes as both a and b have
+// and returns how many that was
+func xorBytes(dst, a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+	return n
+}
@@ -0,0 +1,148 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SP 800-38A section F.5
+var ctrTests = []struct {
+	name       string
+	key        string
+	iv         string
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"F.5.1 CTR-AES128",
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff5ae4df3edbd5d35e5b4f09020db03eab1e031dda2fbe03d1792170a0f3009cee",
+	},
+	{
+		"F.5.3 CTR-AES192",
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"1abc932417521ca24f2b0459fe7e6e0b090339ec0aa6faefd5ccc2c6f4ce8e941e36b26bd1ebc670d1bd1d665620abf74f78a7f6d29809585a97daec58c6b050",
+	},
+	{
+		"F.5.5 CTR-AES256",
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"601ec313775789a5b7a7f504bbf3d228f443e3ca4d62b59aca84e990cacaf5c52b0930daa23de94ce87017ba2d84988ddfc9c58db67aada613c2dd08457941a6",
+	},
+}
+
+func TestCTR(t *testing.T) {
+	for _, test := range ctrTests {
+		c, err := NewCipherBackend(decodeHex(t, test.key), TTable)
+		assert.NoError(t, err)
+
+		iv := decodeHex(t, test.iv)
+		plaintext := decodeHex(t, test.plaintext)
+		ciphertext := decodeHex(t, test.ciphertext)
+
+		for _, width := range []CounterWidth{Counter128, Counter32} {
+			stream, err := NewCTR(c, iv, width)
+			assert.NoError(t, err)
+
+			// in uneven pieces, to cross block boundaries mid call
+			out := make([]byte, len(plaintext))
+			for _, piece := range [][2]int{{0, 5}, {5, 16}, {16, 40}, {40, 64}} {
+				stream.XORKeyStream(out[piece[0]:piece[1]], plaintext[piece[0]:piece[1]])
+			}
+			assert.Equal(t, ciphertext, out, "%s width %d", test.name, width)
+
+			// and decrypt in place
+			stream, err = NewCTR(c, iv, width)
+			assert.NoError(t, err)
+			stream.XORKeyStream(out, out)
+			assert.Equal(t, plaintext, out, "%s width %d", test.name, width)
+		}
+	}
+}
+
+func TestCTRSeek(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, ctrTests[0].key))
+	assert.NoError(t, err)
+	iv := decodeHex(t, ctrTests[0].iv)
+
+	rng := rand.New(rand.NewSource(465))
+	plaintext := make([]byte, 1000)
+	rng.Read(plaintext)
+
+	ciphertext := make([]byte, len(plaintext))
+	gocipher.NewCTR(c, iv).XORKeyStream(ciphertext, plaintext)
+
+	stream, err := NewCTR(c, iv, Counter128)
+	assert.NoError(t, err)
+
+	for _, offset := range []int{999, 0, 17, 16, 500, 15, 256} {
+		stream.Seek(uint64(offset))
+
+		out := make([]byte, len(plaintext)-offset)
+		stream.XORKeyStream(out, ciphertext[offset:])
+		assert.Equal(t, plaintext[offset:], out, "offset %d", offset)
+	}
+}
+
+func TestCTRWraparound(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, ctrTests[0].key))
+	assert.NoError(t, err)
+
+	keystream := func(iv []byte, width CounterWidth) []byte {
+		stream, err := NewCTR(c, iv, width)
+		assert.NoError(t, err)
+
+		ks := make([]byte, 2*BlockSize)
+		stream.XORKeyStream(ks, ks)
+		return ks
+	}
+	encrypt := func(block []byte) []byte {
+		out := make([]byte, BlockSize)
+		c.Encrypt(out, block)
+		return out
+	}
+
+	// a 32-bit counter wraps to zero without carrying into the nonce
+	iv := decodeHex(t, "000102030405060708090a0bffffffff")
+	ks := keystream(iv, Counter32)
+	assert.Equal(t, encrypt(iv), ks[:BlockSize])
+	assert.Equal(t, encrypt(decodeHex(t, "000102030405060708090a0b00000000")), ks[BlockSize:])
+
+	// a 128-bit counter carries all the way up
+	ks = keystream(iv, Counter128)
+	assert.Equal(t, encrypt(decodeHex(t, "000102030405060708090a0c00000000")), ks[BlockSize:])
+
+	// and wraps to zero at the very top
+	iv = decodeHex(t, "ffffffffffffffffffffffffffffffff")
+	ks = keystream(iv, Counter128)
+	assert.Equal(t, encrypt(make([]byte, BlockSize)), ks[BlockSize:])
+
+	// seeking wraps the same way
+	stream, err := NewCTR(c, decodeHex(t, "000102030405060708090a0bfffffffe"), Counter32)
+	assert.NoError(t, err)
+	stream.Seek(3 * BlockSize)
+	ks = make([]byte, BlockSize)
+	stream.XORKeyStream(ks, ks)
+	assert.Equal(t, encrypt(decodeHex(t, "000102030405060708090a0b00000001")), ks)
+}
+
+func TestCTRErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	_, err = NewCTR(c, make([]byte, 12), Counter32)
+	assert.Equal(t, IVSizeError(12), err)
+
+	for _, width := range []CounterWidth{0, 12, 136} {
+		_, err = NewCTR(c, make([]byte, 16), width)
+		assert.Equal(t, ErrCounterWidth, err, "width %d", width)
+	}
+}
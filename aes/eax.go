@@ -0,0 +1,124 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/subtle"
+)
+
+// EAX (Bellare, Rogaway and Wagner) is built from OMAC, which is CMAC with
+// the message prefixed by a block holding a small tweak t. The nonce is
+// OMAC'd with t = 0 into the initial counter block for Counter mode
+// encryption, and the tag is the XOR of that block with the OMAC of the
+// header, t = 1, and of the ciphertext, t = 2. The nonce may be any length.
+
+const (
+	eaxMinTagSize = 4
+	eaxMaxTagSize = 16
+)
+
+type eax struct {
+	b         gocipher.Block
+	k1, k2    []byte
+	nonceSize int
+	tagSize   int
+}
+
+var _ gocipher.AEAD = (*eax)(nil)
+
+// NewEAX returns b wrapped in EAX mode with nonces of nonceSize bytes, which
+// may be any length, and tags of tagSize bytes, between 4 and 16. b must have
+// a 16 byte block size.
+func NewEAX(b gocipher.Block, nonceSize, tagSize int) (gocipher.AEAD, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, BlockSizeError(b.BlockSize())
+	}
+	if nonceSize < 0 {
+		return nil, IVSizeError(nonceSize)
+	}
+	if tagSize < eaxMinTagSize || tagSize > eaxMaxTagSize {
+		return nil, TagSizeError(tagSize)
+	}
+
+	k1, k2 := cmacSubkeys(b)
+	return &eax{b: b, k1: k1, k2: k2, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (e *eax) NonceSize() int {
+	return e.nonceSize
+}
+
+func (e *eax) Overhead() int {
+	return e.tagSize
+}
+
+func (e *eax) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != e.nonceSize {
+		panic("aes: incorrect nonce length given to EAX")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+e.tagSize)
+
+	n := e.omac(0, nonce)
+	e.stream(n).XORKeyStream(out, plaintext)
+
+	var tag [BlockSize]byte
+	e.tag(tag[:], n, out[:len(plaintext)], additionalData)
+	copy(out[len(plaintext):], tag[:e.tagSize])
+
+	return ret
+}
+
+func (e *eax) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != e.nonceSize {
+		panic("aes: incorrect nonce length given to EAX")
+	}
+	if len(ciphertext) < e.tagSize {
+		return nil, ErrAuthentication
+	}
+
+	tag := ciphertext[len(ciphertext)-e.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-e.tagSize]
+
+	n := e.omac(0, nonce)
+
+	var expected [BlockSize]byte
+	e.tag(expected[:], n, ciphertext, additionalData)
+
+	if subtle.ConstantTimeCompare(expected[:e.tagSize], tag) != 1 {
+		return nil, ErrAuthentication
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	e.stream(n).XORKeyStream(out, ciphertext)
+
+	return ret, nil
+}
+
+// omac returns OMAC^t of data, the CMAC of a block holding t followed by data
+func (e *eax) omac(t byte, data []byte) []byte {
+	c := CMAC{b: e.b, k1: e.k1, k2: e.k2, tagSize: BlockSize}
+
+	var prefix [BlockSize]byte
+	prefix[BlockSize-1] = t
+	c.Write(prefix[:])
+	c.Write(data)
+
+	mac := make([]byte, BlockSize)
+	c.sum(mac)
+	return mac
+}
+
+// tag writes N XOR OMAC^1(header) XOR OMAC^2(ciphertext) to tag
+func (e *eax) tag(tag, n, ciphertext, additionalData []byte) {
+	copy(tag, n)
+	xorBytes(tag, tag, e.omac(1, additionalData))
+	xorBytes(tag, tag, e.omac(2, ciphertext))
+}
+
+func (e *eax) stream(n []byte) *CTR {
+	stream, err := NewCTR(e.b, n, Counter128)
+	if err != nil {
+		panic(err)
+	}
+	return stream
+}
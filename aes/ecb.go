@@ -0,0 +1,96 @@
+package aes
+
+import gocipher "crypto/cipher"
+
+// Electronic Codebook mode (SP 800-38A section 6.1) encrypts every block on
+// its own, so equal plaintext blocks give equal ciphertext blocks and the
+// structure of the message shows straight through (the "ECB penguin").
+
+// blocksCrypter is implemented by block ciphers, such as Cipher, that can
+// encrypt or decrypt many blocks in one call faster than one at a time
+type blocksCrypter interface {
+	EncryptBlocks(dst, src []byte)
+	DecryptBlocks(dst, src []byte)
+}
+
+type ecb struct {
+	b         gocipher.Block
+	blockSize int
+}
+
+type ecbEncrypter ecb
+
+// NewECBEncrypter returns a crypto/cipher.BlockMode which encrypts in
+// Electronic Codebook mode.
+//
+// ECB is INSECURE: identical plaintext blocks encrypt to identical ciphertext
+// blocks, which leaks the structure of the message. It is only provided for
+// interoperating with legacy systems and for teaching.
+func NewECBEncrypter(b gocipher.Block) gocipher.BlockMode {
+	return &ecbEncrypter{b: b, blockSize: b.BlockSize()}
+}
+
+func (x *ecbEncrypter) BlockSize() int {
+	return x.blockSize
+}
+
+func (x *ecbEncrypter) CryptBlocks(dst, src []byte) {
+	checkCryptBlocks(dst, src, x.blockSize)
+
+	if bs, ok := x.b.(blocksCrypter); ok {
+		bs.EncryptBlocks(dst, src)
+		return
+	}
+	for i := 0; i < len(src); i += x.blockSize {
+		x.b.Encrypt(dst[i:], src[i:i+x.blockSize])
+	}
+}
+
+type ecbDecrypter ecb
+
+// NewECBDecrypter returns a crypto/cipher.BlockMode which decrypts in
+// Electronic Codebook mode. ECB is insecure, see NewECBEncrypter.
+func NewECBDecrypter(b gocipher.Block) gocipher.BlockMode {
+	return &ecbDecrypter{b: b, blockSize: b.BlockSize()}
+}
+
+func (x *ecbDecrypter) BlockSize() int {
+	return x.blockSize
+}
+
+func (x *ecbDecrypter) CryptBlocks(dst, src []byte) {
+	checkCryptBlocks(dst, src, x.blockSize)
+
+	if bs, ok := x.b.(blocksCrypter); ok {
+		bs.DecryptBlocks(dst, src)
+		return
+	}
+	for i := 0; i < len(src); i += x.blockSize {
+		x.b.Decrypt(dst[i:], src[i:i+x.blockSize])
+	}
+}
+
+// EncryptECB pads plaintext with p and encrypts it in ECB mode, which is
+// insecure (see NewECBEncrypter). It returns ErrNotFullBlocks if p leaves a
+// partial block.
+func EncryptECB(b gocipher.Block, plaintext []byte, p Padding) ([]byte, error) {
+	padded := p.Pad(plaintext, b.BlockSize())
+	if len(padded)%b.BlockSize() != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	out := make([]byte, len(padded))
+	NewECBEncrypter(b).CryptBlocks(out, padded)
+	return out, nil
+}
+
+// DecryptECB decrypts ciphertext in ECB mode and removes the padding p
+func DecryptECB(b gocipher.Block, ciphertext []byte, p Padding) ([]byte, error) {
+	if len(ciphertext)%b.BlockSize() != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	out := make([]byte, len(ciphertext))
+	NewECBDecrypter(b).CryptBlocks(out, ciphertext)
+	return p.Unpad(out, b.BlockSize())
+}
@@ -0,0 +1,99 @@
+package aes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SP 800-38A section F.1
+var ecbTests = []struct {
+	name       string
+	key        string
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"F.1.1 ECB-AES128",
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"3ad77bb40d7a3660a89ecaf32466ef97f5d3d58503b9699de785895a96fdbaaf43b1cd7f598ece23881b00e3ed0306887b0c785e27e8ad3f8223207104725dd4",
+	},
+	{
+		"F.1.3 ECB-AES192",
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"bd334f1d6e45f25ff712a214571fa5cc974104846d0ad3ad7734ecb3ecee4eefef7afd2270e2e60adce0ba2face6444e9a4b41ba738d6c72fb16691603c18e0e",
+	},
+	{
+		"F.1.5 ECB-AES256",
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"f3eed1bdb5d2a03c064b5a7e3db181f8591ccb10d410ed26dc5ba74a31362870b6ed21b99ca6f4f9f153e7b1beafed1d23304b7a39f9f3ff067d8d8f9e24ecc7",
+	},
+}
+
+func TestECB(t *testing.T) {
+	for _, test := range ecbTests {
+		for _, backend := range []Backend{Reference, TTable, Bitsliced} {
+			c, err := NewCipherBackend(decodeHex(t, test.key), backend)
+			assert.NoError(t, err)
+
+			plaintext := decodeHex(t, test.plaintext)
+			ciphertext := decodeHex(t, test.ciphertext)
+			out := make([]byte, len(plaintext))
+
+			NewECBEncrypter(c).CryptBlocks(out, plaintext)
+			assert.Equal(t, ciphertext, out, "%s %s", test.name, backend)
+
+			NewECBDecrypter(c).CryptBlocks(out, out)
+			assert.Equal(t, plaintext, out, "%s %s", test.name, backend)
+		}
+	}
+}
+
+func TestECBPadded(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, ecbTests[0].key))
+	assert.NoError(t, err)
+
+	for n := 0; n <= 3*BlockSize; n++ {
+		plaintext := bytes.Repeat([]byte{0x42}, n)
+
+		ciphertext, err := EncryptECB(c, plaintext, PKCS7{})
+		assert.NoError(t, err)
+		assert.Equal(t, (n/BlockSize+1)*BlockSize, len(ciphertext))
+
+		out, err := DecryptECB(c, ciphertext, PKCS7{})
+		assert.NoError(t, err)
+		assert.Equal(t, plaintext, out)
+	}
+
+	// the penguin: repeated plaintext blocks show up as repeated ciphertext
+	ciphertext, err := EncryptECB(c, bytes.Repeat([]byte("sixteen byte blk"), 3), NoPadding{})
+	assert.NoError(t, err)
+	assert.Equal(t, ciphertext[:BlockSize], ciphertext[BlockSize:2*BlockSize])
+	assert.Equal(t, ciphertext[:BlockSize], ciphertext[2*BlockSize:])
+
+	_, err = EncryptECB(c, make([]byte, 17), NoPadding{})
+	assert.Equal(t, ErrNotFullBlocks, err)
+
+	_, err = DecryptECB(c, make([]byte, 17), PKCS7{})
+	assert.Equal(t, ErrNotFullBlocks, err)
+
+	// decrypting with the wrong key leaves garbage where the padding should be
+	ciphertext, err = EncryptECB(c, []byte("attack at dawn"), PKCS7{})
+	assert.NoError(t, err)
+	other, err := NewCipher(decodeHex(t, ecbTests[1].key))
+	assert.NoError(t, err)
+	_, err = DecryptECB(other, ciphertext, PKCS7{})
+	assert.Equal(t, ErrInvalidPadding, err)
+}
+
+func TestECBPartialBlock(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	assert.Panics(t, func() { NewECBEncrypter(c).CryptBlocks(make([]byte, 32), make([]byte, 20)) })
+	assert.Panics(t, func() { NewECBDecrypter(c).CryptBlocks(make([]byte, 16), make([]byte, 32)) })
+}
@@ -0,0 +1,96 @@
+package aes
+
+import (
+	"errors"
+	"strconv"
+)
+
+// KeySizeError is returned when a key is not 16, 24 or 32 bytes long
+type KeySizeError int
+
+func (k KeySizeError) Error() string {
+	return "aes: invalid key size " + strconv.Itoa(int(k))
+}
+
+// BlockSizeError is returned when an input is not exactly one BlockSize
+// byte block long
+type BlockSizeError int
+
+func (b BlockSizeError) Error() string {
+	return "aes: invalid block size " + strconv.Itoa(int(b))
+}
+
+func checkKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	}
+	return KeySizeError(len(key))
+}
+
+func checkBlock(in []byte) error {
+	if len(in) != BlockSize {
+		return BlockSizeError(len(in))
+	}
+	return nil
+}
+
+// BackendError is returned when a Cipher is requested with an unknown Backend
+type BackendError Backend
+
+func (b BackendError) Error() string {
+	return "aes: unknown backend " + strconv.Itoa(int(b))
+}
+
+// ErrNotFullBlocks is returned when a mode that works on whole blocks is given
+// input that is not a multiple of the block size
+var ErrNotFullBlocks = errors.New("aes: input not a whole number of blocks")
+
+// ErrInvalidPadding is returned when padding removed after decryption is
+// malformed, which usually means the wrong key or a corrupted ciphertext
+var ErrInvalidPadding = errors.New("aes: invalid padding")
+
+// IVSizeError is returned when an initialization vector, nonce or tweak has
+// the wrong length for the mode it is given to
+type IVSizeError int
+
+func (i IVSizeError) Error() string {
+	return "aes: invalid IV size " + strconv.Itoa(int(i))
+}
+
+// TagSizeError is returned when an authenticated mode is asked for a tag
+// length it does not support
+type TagSizeError int
+
+func (t TagSizeError) Error() string {
+	return "aes: invalid tag size " + strconv.Itoa(int(t))
+}
+
+// ErrAuthentication is returned when an authenticated mode is asked to open a
+// message that fails its integrity check. It deliberately says nothing about
+// what was wrong with the message.
+var ErrAuthentication = errors.New("aes: message authentication failed")
+
+// ErrWrapLength is returned when a key to be wrapped, or a wrapped key to be
+// unwrapped, has a length the key wrap algorithm does not accept
+var ErrWrapLength = errors.New("aes: invalid length for key wrap")
+
+// ErrShortInput is returned when a mode that needs at least one whole block,
+// such as CBC with ciphertext stealing, is given less
+var ErrShortInput = errors.New("aes: input shorter than one block")
+
+// ErrCounterWidth is returned when a CTR counter width is not a multiple of 8
+// between 8 and 128 bits
+var ErrCounterWidth = errors.New("aes: counter width must be a multiple of 8 between 8 and 128")
+
+// ErrSegmentSize is returned when a CFB segment size is neither 1 nor a
+// multiple of 8 bits up to the block size
+var ErrSegmentSize = errors.New("aes: CFB segment size must be 1 or a multiple of 8 up to the block size")
+
+// ErrPMACWorkers is returned when a parallel PMAC is asked for fewer than one
+// worker
+var ErrPMACWorkers = errors.New("aes: PMAC needs at least one worker")
+
+// ErrCSVariant is returned when CBC with ciphertext stealing is asked for a
+// variant other than CS1, CS2 or CS3
+var ErrCSVariant = errors.New("aes: ciphertext stealing variant must be CS1, CS2 or CS3")
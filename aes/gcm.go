@@ -0,0 +1,168 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/subtle"
+)
+
+// Galois/Counter Mode (SP 800-38D) encrypts in Counter mode with a 32-bit
+// counter and authenticates the additional data and the ciphertext with
+// GHASH, keyed with the encryption of the zero block.
+
+const (
+	gcmStandardNonceSize = 12
+	gcmTagSize           = 16
+	gcmMinTagSize        = 12
+
+	// SP 800-38D limits the plaintext to 2^39 - 256 bits
+	gcmMaxPlaintext = 1<<36 - 32
+)
+
+type gcm struct {
+	b         gocipher.Block
+	h         []byte
+	nonceSize int
+	tagSize   int
+}
+
+var _ gocipher.AEAD = (*gcm)(nil)
+
+// NewGCM returns b wrapped in Galois/Counter Mode with the standard 12 byte
+// nonce and 16 byte tag. b must have a 16 byte block size.
+func NewGCM(b gocipher.Block) (gocipher.AEAD, error) {
+	return newGCM(b, gcmStandardNonceSize, gcmTagSize)
+}
+
+// NewGCMWithNonceSize is NewGCM with a nonce of any non-zero length. Nonces
+// other than 12 bytes are hashed into the initial counter block, which costs
+// an extra GHASH and is only worth it for interoperability.
+func NewGCMWithNonceSize(b gocipher.Block, size int) (gocipher.AEAD, error) {
+	return newGCM(b, size, gcmTagSize)
+}
+
+// NewGCMWithTagSize is NewGCM with the tag truncated to tagSize bytes, which
+// must be between 12 and 16
+func NewGCMWithTagSize(b gocipher.Block, tagSize int) (gocipher.AEAD, error) {
+	return newGCM(b, gcmStandardNonceSize, tagSize)
+}
+
+func newGCM(b gocipher.Block, nonceSize, tagSize int) (*gcm, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, BlockSizeError(b.BlockSize())
+	}
+	if nonceSize <= 0 {
+		return nil, IVSizeError(nonceSize)
+	}
+	if tagSize < gcmMinTagSize || tagSize > gcmTagSize {
+		return nil, TagSizeError(tagSize)
+	}
+
+	h := make([]byte, BlockSize)
+	b.Encrypt(h, h)
+
+	return &gcm{b: b, h: h, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (g *gcm) NonceSize() int {
+	return g.nonceSize
+}
+
+func (g *gcm) Overhead() int {
+	return g.tagSize
+}
+
+func (g *gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != g.nonceSize {
+		panic("aes: incorrect nonce length given to GCM")
+	}
+	if uint64(len(plaintext)) > gcmMaxPlaintext {
+		panic("aes: message too large for GCM")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+g.tagSize)
+
+	stream, tagMask := g.start(nonce)
+	stream.XORKeyStream(out, plaintext)
+
+	var tag [gcmTagSize]byte
+	g.auth(tag[:], out[:len(plaintext)], additionalData, tagMask)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+func (g *gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != g.nonceSize {
+		panic("aes: incorrect nonce length given to GCM")
+	}
+	if len(ciphertext) < g.tagSize || uint64(len(ciphertext)) > gcmMaxPlaintext+uint64(g.tagSize) {
+		return nil, ErrAuthentication
+	}
+
+	tag := ciphertext[len(ciphertext)-g.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-g.tagSize]
+
+	stream, tagMask := g.start(nonce)
+
+	var expected [gcmTagSize]byte
+	g.auth(expected[:], ciphertext, additionalData, tagMask)
+
+	if subtle.ConstantTimeCompare(expected[:g.tagSize], tag) != 1 {
+		return nil, ErrAuthentication
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	stream.XORKeyStream(out, ciphertext)
+
+	return ret, nil
+}
+
+// start derives the pre-counter block J0 from nonce and returns a Counter
+// mode stream positioned at inc32(J0), along with E(J0) for masking the tag
+func (g *gcm) start(nonce []byte) (*CTR, []byte) {
+	j0 := make([]byte, BlockSize)
+	if len(nonce) == gcmStandardNonceSize {
+		copy(j0, nonce)
+		j0[BlockSize-1] = 1
+	} else {
+		h := newGHASH(g.h)
+		h.update(nonce)
+		h.updateLengths(0, len(nonce))
+		h.sum(j0)
+	}
+
+	stream, err := NewCTR(g.b, j0, Counter32)
+	if err != nil {
+		panic(err)
+	}
+
+	// the first keystream block is E(J0), and the message starts after it
+	tagMask := make([]byte, BlockSize)
+	stream.XORKeyStream(tagMask, tagMask)
+
+	return stream, tagMask
+}
+
+// auth writes GHASH(additionalData, ciphertext) XOR tagMask to tag
+func (g *gcm) auth(tag, ciphertext, additionalData, tagMask []byte) {
+	h := newGHASH(g.h)
+	h.update(additionalData)
+	h.update(ciphertext)
+	h.updateLengths(len(additionalData), len(ciphertext))
+	h.sum(tag)
+
+	xorBytes(tag, tag, tagMask)
+}
+
+// sliceForAppend extends in by n bytes, reusing its capacity if there is
+// enough, and returns the whole slice along with the n new bytes
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,171 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// AES-GCM-SIV (RFC 8452) derives a fresh authentication key and encryption
+// key from the master key for every nonce, computes POLYVAL over the
+// additional data and plaintext, and encrypts the result into a tag that is
+// also the initial counter block. As with SIV, reusing a nonce reveals only
+// whether two messages were equal.
+
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+
+	// RFC 8452 section 6 limits the plaintext and additional data to 2^36
+	// bytes
+	gcmSIVMaxLength = 1 << 36
+)
+
+type gcmSIV struct {
+	master  *Cipher
+	keySize int
+	backend Backend
+}
+
+var _ gocipher.AEAD = (*gcmSIV)(nil)
+
+// NewGCMSIV returns AES-GCM-SIV under key, which must be 16 or 32 bytes,
+// with its block encryption done by the Reference backend
+func NewGCMSIV(key []byte) (gocipher.AEAD, error) {
+	return NewGCMSIVBackend(key, Reference)
+}
+
+// NewGCMSIVBackend is NewGCMSIV with the master key and every per-nonce key
+// expanded for the given Backend
+func NewGCMSIVBackend(key []byte, b Backend) (gocipher.AEAD, error) {
+	if len(key) != 16 && len(key) != 32 {
+		return nil, KeySizeError(len(key))
+	}
+
+	master, err := NewCipherBackend(key, b)
+	if err != nil {
+		return nil, err
+	}
+	return &gcmSIV{master: master, keySize: len(key), backend: b}, nil
+}
+
+func (g *gcmSIV) NonceSize() int {
+	return gcmSIVNonceSize
+}
+
+func (g *gcmSIV) Overhead() int {
+	return gcmSIVTagSize
+}
+
+func (g *gcmSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("aes: incorrect nonce length given to GCM-SIV")
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxLength || uint64(len(additionalData)) > gcmSIVMaxLength {
+		panic("aes: message too large for GCM-SIV")
+	}
+
+	authKey, enc := g.deriveKeys(nonce)
+
+	var tag [gcmSIVTagSize]byte
+	g.tag(tag[:], authKey, enc, nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSIVTagSize)
+	gcmSIVCTR(enc, tag[:], out, plaintext)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+func (g *gcmSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("aes: incorrect nonce length given to GCM-SIV")
+	}
+	if len(ciphertext) < gcmSIVTagSize ||
+		uint64(len(ciphertext)) > gcmSIVMaxLength+gcmSIVTagSize ||
+		uint64(len(additionalData)) > gcmSIVMaxLength {
+		return nil, ErrAuthentication
+	}
+
+	var tag [gcmSIVTagSize]byte
+	copy(tag[:], ciphertext[len(ciphertext)-gcmSIVTagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-gcmSIVTagSize]
+
+	authKey, enc := g.deriveKeys(nonce)
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	gcmSIVCTR(enc, tag[:], out, ciphertext)
+
+	var expected [gcmSIVTagSize]byte
+	g.tag(expected[:], authKey, enc, nonce, out, additionalData)
+
+	if subtle.ConstantTimeCompare(expected[:], tag[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, ErrAuthentication
+	}
+
+	return ret, nil
+}
+
+// deriveKeys returns the message authentication key and a Cipher under the
+// message encryption key for nonce, RFC 8452 section 4. Each eight bytes of
+// key material is the start of the encryption of a little-endian counter
+// followed by the nonce.
+func (g *gcmSIV) deriveKeys(nonce []byte) ([]byte, *Cipher) {
+	material := make([]byte, 16+g.keySize)
+
+	var in, out [BlockSize]byte
+	copy(in[4:], nonce)
+	for i := 0; i*8 < len(material); i++ {
+		binary.LittleEndian.PutUint32(in[:4], uint32(i))
+		g.master.Encrypt(out[:], in[:])
+		copy(material[i*8:], out[:8])
+	}
+
+	enc, err := NewCipherBackend(material[16:], g.backend)
+	if err != nil {
+		panic(err)
+	}
+	return material[:16], enc
+}
+
+// tag writes the tag for plaintext and additionalData to tag: POLYVAL of the
+// two and their lengths, XORed with the nonce, with the top bit cleared and
+// encrypted
+func (g *gcmSIV) tag(tag, authKey []byte, enc *Cipher, nonce, plaintext, additionalData []byte) {
+	var lengths [BlockSize]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+
+	p := newPOLYVAL(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+	p.update(lengths[:])
+
+	var s [BlockSize]byte
+	p.sum(s[:])
+	xorBytes(s[:], s[:], nonce)
+	s[BlockSize-1] &= 0x7f
+
+	enc.Encrypt(tag, s[:])
+}
+
+// gcmSIVCTR is the Counter mode of RFC 8452: the initial counter block is
+// the tag with its top bit set, and only its first four bytes count, as a
+// little-endian integer that wraps around
+func gcmSIVCTR(enc *Cipher, tag, dst, src []byte) {
+	var ctr, ks [BlockSize]byte
+	copy(ctr[:], tag)
+	ctr[BlockSize-1] |= 0x80
+
+	for len(src) > 0 {
+		enc.Encrypt(ks[:], ctr[:])
+		n := xorBytes(dst, src, ks[:])
+		src = src[n:]
+		dst = dst[n:]
+
+		binary.LittleEndian.PutUint32(ctr[:4], binary.LittleEndian.Uint32(ctr[:4])+1)
+	}
+}
@@ -0,0 +1,217 @@
+package aes
+
+import "encoding/binary"
+
+// GHASH (SP 800-38D section 6.4) works in GF(2^128) defined by the polynomial
+// x^128 + x^7 + x^2 + x + 1, with the bits of each block reflected: the most
+// significant bit of the first byte is the coefficient of x^0.
+
+// fieldElement is an element of GF(2^128) in GCM bit order. hi holds the
+// first eight bytes of the block and lo the last eight, both big-endian.
+type fieldElement struct {
+	hi, lo uint64
+}
+
+// gcmReduce is the reduction polynomial R of SP 800-38D Algorithm 1, the
+// byte 11100001 followed by 120 zero bits
+const gcmReduce = 0xe1 << 56
+
+func loadFieldElement(b []byte) fieldElement {
+	return fieldElement{binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:16])}
+}
+
+func (x fieldElement) store(b []byte) {
+	binary.BigEndian.PutUint64(b[:8], x.hi)
+	binary.BigEndian.PutUint64(b[8:16], x.lo)
+}
+
+func (x fieldElement) add(y fieldElement) fieldElement {
+	return fieldElement{x.hi ^ y.hi, x.lo ^ y.lo}
+}
+
+// mulX multiplies x by the polynomial x, which in GCM bit order is a shift
+// right followed by a reduction if a bit fell off the end
+func (x fieldElement) mulX() fieldElement {
+	mask := -(x.lo & 1)
+	return fieldElement{x.hi>>1 ^ gcmReduce&mask, x.lo>>1 | x.hi<<63}
+}
+
+// gcmMultiply is SP 800-38D Algorithm 1. Masks take the place of its
+// branches so that the running time does not depend on x or y.
+func gcmMultiply(x, y fieldElement) fieldElement {
+	var z fieldElement
+	v := y
+	for i := 0; i < 128; i++ {
+		word := x.hi
+		if i >= 64 {
+			word = x.lo
+		}
+		mask := -(word >> (63 - uint(i%64)) & 1)
+		z.hi ^= v.hi & mask
+		z.lo ^= v.lo & mask
+		v = v.mulX()
+	}
+	return z
+}
+
+// ghash accumulates GHASH_H over a sequence of blocks
+type ghash struct {
+	h fieldElement
+	y fieldElement
+}
+
+func newGHASH(h []byte) ghash {
+	return ghash{h: loadFieldElement(h)}
+}
+
+// update absorbs data, padding a trailing partial block with zeros as GCM
+// does at the end of the additional data and of the ciphertext
+func (g *ghash) update(data []byte) {
+	for len(data) >= BlockSize {
+		g.y = gcmMultiply(g.y.add(loadFieldElement(data)), g.h)
+		data = data[BlockSize:]
+	}
+
+	if len(data) > 0 {
+		var block [BlockSize]byte
+		copy(block[:], data)
+		g.y = gcmMultiply(g.y.add(loadFieldElement(block[:])), g.h)
+	}
+}
+
+// updateLengths absorbs the final block holding the bit lengths of the two
+// inputs
+func (g *ghash) updateLengths(aLen, cLen int) {
+	g.update(gcmLengths(uint64(aLen), uint64(cLen)))
+}
+
+func (g *ghash) sum(b []byte) {
+	g.y.store(b)
+}
+
+// polyval accumulates POLYVAL_H (RFC 8452 section 3), which is GHASH with
+// the bytes of every block reversed and the key multiplied by x, as RFC 8452
+// appendix A shows
+type polyval struct {
+	g ghash
+}
+
+func newPOLYVAL(h []byte) polyval {
+	var key [BlockSize]byte
+	reverseBlock(key[:], h)
+	return polyval{ghash{h: loadFieldElement(key[:]).mulX()}}
+}
+
+// update absorbs data, padding a trailing partial block with zeros
+func (p *polyval) update(data []byte) {
+	var block [BlockSize]byte
+	for len(data) > 0 {
+		var in [BlockSize]byte
+		n := copy(in[:], data)
+		reverseBlock(block[:], in[:])
+		p.g.update(block[:])
+		data = data[n:]
+	}
+}
+
+func (p *polyval) sum(b []byte) {
+	var block [BlockSize]byte
+	p.g.sum(block[:])
+	reverseBlock(b, block[:])
+}
+
+func reverseBlock(dst, src []byte) {
+	for i := 0; i < BlockSize; i++ {
+		dst[i] = src[BlockSize-1-i]
+	}
+}
+
+// mulAlpha multiplies an XTS tweak by the primitive element alpha, the
+// polynomial x. XTS uses the same field as GCM but stores it little-endian
+// with x^0 in the low bit of the first byte, so this is xtime carried across
+// sixteen bytes, with the x^128 overflow reduced by x^7 + x^2 + x + 1.
+func mulAlpha(t []byte) {
+	var carry byte
+	for i := range t[:BlockSize] {
+		next := t[i] >> 7
+		t[i] = t[i]<<1 | carry
+		carry = next
+	}
+	t[0] ^= 0x87 & -carry
+}
+
+// double multiplies b by x in the big-endian representation that CMAC and
+// SIV use, where the first bit of the block is the coefficient of x^127: a
+// shift left across the block, with the overflow reduced into the last byte
+func double(b []byte) {
+	var carry byte
+	for i := BlockSize - 1; i >= 0; i-- {
+		next := b[i] >> 7
+		b[i] = b[i]<<1 | carry
+		carry = next
+	}
+	b[BlockSize-1] ^= 0x87 & -carry
+}
+
+// halve divides b by x, undoing double. A b with the x^0 bit set is first
+// made divisible by adding x^128 + x^7 + x^2 + x + 1, which after the shift
+// right leaves x^127 in the first bit and 0x43 in the last byte.
+func halve(b []byte) {
+	carry := b[BlockSize-1] & 1
+	for i := BlockSize - 1; i > 0; i-- {
+		b[i] = b[i]>>1 | b[i-1]<<7
+	}
+	b[0] >>= 1
+
+	b[0] ^= 0x80 & -carry
+	b[BlockSize-1] ^= 0x43 & -carry
+}
+
+// gcmTable holds the products of H with every four bit polynomial, so that
+// gcmTable.mul multiplies four bits of y at a time (Shoup's method, as in the
+// GCM paper section 4.1) instead of one. Its lookups are indexed by the data,
+// so unlike gcmMultiply its running time is not independent of the data on
+// machines with a cache.
+type gcmTable [16]fieldElement
+
+// gcmTableReduction[m] is what the four bits m shifted off the end of the
+// accumulator reduce to, placed in the top 16 bits
+var gcmTableReduction = [16]uint16{
+	0x0000, 0x1c20, 0x3840, 0x2460, 0x7080, 0x6ca0, 0x48c0, 0x54e0,
+	0xe100, 0xfd20, 0xd940, 0xc560, 0x9180, 0x8da0, 0xa9c0, 0xb5e0,
+}
+
+// newGCMTable computes the table for h. Entries are indexed by the four bits
+// of y reversed, since the lowest bit of each nibble is the highest power of
+// x in it.
+func newGCMTable(h fieldElement) *gcmTable {
+	t := new(gcmTable)
+	t[reverseNibble(1)] = h
+	for i := 2; i < 16; i += 2 {
+		t[reverseNibble(i)] = t[reverseNibble(i/2)].mulX()
+		t[reverseNibble(i+1)] = t[reverseNibble(i)].add(h)
+	}
+	return t
+}
+
+// mul returns y * H, taking the nibbles of y from the last, highest degree,
+// one to the first and multiplying the accumulator by x^4 between them
+func (t *gcmTable) mul(y fieldElement) fieldElement {
+	var z fieldElement
+	for _, word := range [2]uint64{y.lo, y.hi} {
+		for j := 0; j < 64; j += 4 {
+			msw := z.lo & 0xf
+			z.lo = z.lo>>4 | z.hi<<60
+			z.hi >>= 4
+			z.hi ^= uint64(gcmTableReduction[msw]) << 48
+
+			z = z.add(t[word&0xf])
+			word >>= 4
+		}
+	}
+	return z
+}
+
+func reverseNibble(i int) int {
+	return (i&1)<<3 | (i&2)<<1 | (i&4)>>1 | (i&8)>>3
+}
@@ -0,0 +1,232 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"encoding/binary"
+	"hash"
+)
+
+// GMAC (SP 800-38D section 3) is GCM with no plaintext: the tag of the
+// additional data alone. GHASH is the universal hash under both of them,
+// which multiplies each block, added to the running value, by the hash key H
+// in GF(2^128).
+
+// GHASH is GHASH_H of SP 800-38D section 6.4 over everything written to it,
+// with a partial last block padded with zeros. It implements hash.Hash.
+// Multiplication by H uses a table of multiples of H (Shoup's 4-bit method),
+// which is several times faster than the bit at a time multiplication GCM
+// uses, but the table is indexed by the data, so on a machine with a cache its
+// timing can leak information about the data and H. That gives up the
+// constant-time property of the rest of the package; it is here to show the
+// method and for hashing public data, and GMAC and GCM do not use it.
+type GHASH struct {
+	table *gcmTable
+	y     fieldElement
+	buf   [BlockSize]byte
+	n     int
+}
+
+var _ hash.Hash = (*GHASH)(nil)
+
+// NewGHASH returns GHASH under the hash key h, which must be 16 bytes. GCM's
+// hash key is the encryption of the zero block. See GHASH for why the result
+// should not be used where H or the data must stay secret.
+func NewGHASH(h []byte) (*GHASH, error) {
+	if len(h) != BlockSize {
+		return nil, KeySizeError(len(h))
+	}
+	return &GHASH{table: newGCMTable(loadFieldElement(h))}, nil
+}
+
+// Write adds p to the input. It never returns an error.
+func (g *GHASH) Write(p []byte) (int, error) {
+	written := len(p)
+
+	if g.n > 0 {
+		n := copy(g.buf[g.n:], p)
+		g.n += n
+		p = p[n:]
+		if g.n < BlockSize {
+			return written, nil
+		}
+
+		g.block(g.buf[:])
+		g.n = 0
+	}
+
+	for len(p) >= BlockSize {
+		g.block(p[:BlockSize])
+		p = p[BlockSize:]
+	}
+
+	g.n = copy(g.buf[:], p)
+	return written, nil
+}
+
+// Pad completes a partial block with zeros, as GCM does at the end of the
+// additional data, so that what is written next starts a new block
+func (g *GHASH) Pad() {
+	if g.n > 0 {
+		g.y = g.padded()
+		g.n = 0
+	}
+}
+
+// Sum appends the hash of the input so far, zero padded, to b. It does not
+// change the state.
+func (g *GHASH) Sum(b []byte) []byte {
+	y := g.y
+	if g.n > 0 {
+		y = g.padded()
+	}
+
+	var out [BlockSize]byte
+	y.store(out[:])
+	return append(b, out[:]...)
+}
+
+// Reset clears the input, keeping the hash key
+func (g *GHASH) Reset() {
+	g.y = fieldElement{}
+	g.n = 0
+}
+
+// Size returns the hash size, 16 bytes
+func (g *GHASH) Size() int {
+	return BlockSize
+}
+
+// BlockSize returns the block size, 16 bytes
+func (g *GHASH) BlockSize() int {
+	return BlockSize
+}
+
+func (g *GHASH) block(b []byte) {
+	g.y = g.table.mul(g.y.add(loadFieldElement(b)))
+}
+
+// padded returns the hash after the partial block in buf, zero padded
+func (g *GHASH) padded() fieldElement {
+	var block [BlockSize]byte
+	copy(block[:], g.buf[:g.n])
+	return g.table.mul(g.y.add(loadFieldElement(block[:])))
+}
+
+// GMAC is a GMAC computation in progress under one key and IV. It
+// implements hash.Hash. An IV must never be used for two different messages
+// under the same key, so Reset is only for authenticating the same message
+// again; ResetIV starts a new one. It uses the same constant-time GHASH as
+// GCM.
+type GMAC struct {
+	b        gocipher.Block
+	ghash    ghash
+	tagMask  [BlockSize]byte
+	buf      [BlockSize]byte
+	n        int
+	adLength uint64
+}
+
+var _ hash.Hash = (*GMAC)(nil)
+
+// NewGMAC returns GMAC under b with the given IV, which may be any non-zero
+// length, 12 bytes being the standard. b must have a 16 byte block size.
+func NewGMAC(b gocipher.Block, iv []byte) (*GMAC, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, BlockSizeError(b.BlockSize())
+	}
+
+	h := make([]byte, BlockSize)
+	b.Encrypt(h, h)
+
+	m := &GMAC{b: b, ghash: newGHASH(h)}
+	if err := m.ResetIV(iv); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ResetIV starts a new message with a new IV, keeping the key
+func (m *GMAC) ResetIV(iv []byte) error {
+	if len(iv) == 0 {
+		return IVSizeError(len(iv))
+	}
+
+	// J0 is the IV followed by a 32-bit 1 for a 12 byte IV, as in GCM, and
+	// the GHASH of the IV and its length otherwise
+	var j0 [BlockSize]byte
+	if len(iv) == gcmStandardNonceSize {
+		copy(j0[:], iv)
+		j0[BlockSize-1] = 1
+	} else {
+		g := ghash{h: m.ghash.h}
+		g.update(iv)
+		g.updateLengths(0, len(iv))
+		g.sum(j0[:])
+	}
+	m.b.Encrypt(m.tagMask[:], j0[:])
+
+	m.Reset()
+	return nil
+}
+
+// Write adds p to the authenticated data. It never returns an error.
+func (m *GMAC) Write(p []byte) (int, error) {
+	written := len(p)
+	m.adLength += uint64(len(p))
+
+	if m.n > 0 {
+		n := copy(m.buf[m.n:], p)
+		m.n += n
+		p = p[n:]
+		if m.n < BlockSize {
+			return written, nil
+		}
+
+		m.ghash.update(m.buf[:])
+		m.n = 0
+	}
+
+	whole := len(p) / BlockSize * BlockSize
+	m.ghash.update(p[:whole])
+	m.n = copy(m.buf[:], p[whole:])
+	return written, nil
+}
+
+// Sum appends the tag of the data written so far to b. It does not change
+// the state.
+func (m *GMAC) Sum(b []byte) []byte {
+	g := m.ghash
+	g.update(m.buf[:m.n])
+	g.update(gcmLengths(m.adLength, 0))
+
+	var tag [BlockSize]byte
+	g.sum(tag[:])
+	xorBytes(tag[:], tag[:], m.tagMask[:])
+	return append(b, tag[:]...)
+}
+
+// Reset starts the message again under the same key and IV
+func (m *GMAC) Reset() {
+	m.ghash.y = fieldElement{}
+	m.n = 0
+	m.adLength = 0
+}
+
+// Size returns the tag size, 16 bytes
+func (m *GMAC) Size() int {
+	return BlockSize
+}
+
+// BlockSize returns the block size, 16 bytes
+func (m *GMAC) BlockSize() int {
+	return BlockSize
+}
+
+// gcmLengths returns the final GHASH block holding the bit lengths of the
+// additional data and the ciphertext
+func gcmLengths(adLength, ctLength uint64) []byte {
+	block := make([]byte, BlockSize)
+	binary.BigEndian.PutUint64(block[:8], adLength*8)
+	binary.BigEndian.PutUint64(block[8:], ctLength*8)
+	return block
+}
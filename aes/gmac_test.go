@@ -0,0 +1,190 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// GMAC of the McGrew and Viega keys and IVs over their additional data,
+// Test Case 1 being theirs and the others from OpenSSL's GMAC. The third has a
+// 64-bit IV and the fourth a 192-bit one.
+var gmacTests = []struct {
+	key string
+	iv  string
+	ad  string
+	tag string
+}{
+	{
+		"00000000000000000000000000000000",
+		"000000000000000000000000",
+		"",
+		"58e2fccefa7e3061367f1d57a4e7455a",
+	},
+	{
+		"feffe9928665731c6d6a8f9467308308",
+		"cafebabefacedbaddecaf888",
+		"feedfacedeadbeeffeedfacedeadbeefabaddad2",
+		"346434fd51d5cd0c5887ec63e39b907a",
+	},
+	{
+		"feffe9928665731c6d6a8f9467308308",
+		"cafebabefacedbad",
+		"feedfacedeadbeeffeedfacedeadbeefabaddad2",
+		"ef6995e531e81a01f5b2f7762cc60bd2",
+	},
+	{
+		"feffe9928665731c6d6a8f9467308308feffe9928665731c",
+		"feedfacedeadbeef0102030405060708090a0b0c0d0e0f1011121314151617",
+		"000102030405060708090a0b0c0d0e0f",
+		"f3347e6fea33ec15039e13ab4006146f",
+	},
+	{
+		"feffe9928665731c6d6a8f9467308308feffe9928665731c6d6a8f9467308308",
+		"cafebabefacedbaddecaf888",
+		"d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39",
+		"9bb125474e3ab02250391b72e0bbd87a",
+	},
+}
+
+func TestGMAC(t *testing.T) {
+	for _, test := range gmacTests {
+		c, err := NewCipher(decodeHex(t, test.key))
+		assert.NoError(t, err)
+
+		mac, err := NewGMAC(c, decodeHex(t, test.iv))
+		assert.NoError(t, err)
+		assert.Equal(t, 16, mac.Size())
+
+		mac.Write(decodeHex(t, test.ad))
+		assert.Equal(t, decodeHex(t, test.tag), mac.Sum(nil), test.key)
+
+		// GMAC is GCM with no plaintext
+		aead, err := NewGCMWithNonceSize(c, len(test.iv)/2)
+		assert.NoError(t, err)
+		assert.Equal(t, decodeHex(t, test.tag), aead.Seal(nil, decodeHex(t, test.iv), nil, decodeHex(t, test.ad)), test.key)
+	}
+}
+
+func TestGMACStdlib(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	key := make([]byte, 16)
+	rng.Read(key)
+	c, err := NewCipherBackend(key, TTable)
+	assert.NoError(t, err)
+
+	iv := make([]byte, 12)
+	mac, err := NewGMAC(c, iv)
+	assert.NoError(t, err)
+	std, err := gocipher.NewGCM(c)
+	assert.NoError(t, err)
+
+	for n := 0; n <= 100; n++ {
+		rng.Read(iv)
+		ad := make([]byte, n)
+		rng.Read(ad)
+
+		assert.NoError(t, mac.ResetIV(iv))
+		for rest := ad; len(rest) > 0; {
+			k := rng.Intn(len(rest) + 1)
+			mac.Write(rest[:k])
+			mac.Sum(nil)
+			rest = rest[k:]
+		}
+		assert.Equal(t, std.Seal(nil, iv, nil, ad), mac.Sum(nil), "length %d", n)
+	}
+}
+
+func TestGHASHTable(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	block := make([]byte, BlockSize)
+
+	for i := 0; i < 100; i++ {
+		rng.Read(block)
+		x := loadFieldElement(block)
+		rng.Read(block)
+		y := loadFieldElement(block)
+
+		assert.Equal(t, gcmMultiply(x, y), newGCMTable(y).mul(x))
+	}
+}
+
+func TestNewGHASH(t *testing.T) {
+	// RFC 8452 appendix A
+	g, err := NewGHASH(decodeHex(t, "25629347589242761d31f826ba4b757b"))
+	assert.NoError(t, err)
+	g.Write(decodeHex(t, "4f4f95668c83dfb6401762bb2d01a262"))
+	g.Write(decodeHex(t, "d1a24ddd2721d006bbe45f20d3c9f362"))
+	assert.Equal(t, decodeHex(t, "bd9b3997046731fb96251b91f9c99d7a"), g.Sum(nil))
+
+	// the same as the internal ghash for writes of any length, with Pad
+	// matching the padding between GCM's inputs
+	rng := rand.New(rand.NewSource(465))
+	h := make([]byte, BlockSize)
+	rng.Read(h)
+	g, err = NewGHASH(h)
+	assert.NoError(t, err)
+
+	for n := 0; n <= 50; n++ {
+		a := make([]byte, n)
+		b := make([]byte, 50-n)
+		rng.Read(a)
+		rng.Read(b)
+
+		ref := newGHASH(h)
+		ref.update(a)
+		ref.update(b)
+		expected := make([]byte, BlockSize)
+		ref.sum(expected)
+
+		g.Reset()
+		g.Write(a)
+		g.Pad()
+		g.Write(b)
+		assert.Equal(t, expected, g.Sum(nil), "length %d", n)
+	}
+}
+
+func TestGMACErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	_, err = NewGMAC(c, nil)
+	assert.Equal(t, IVSizeError(0), err)
+
+	mac, err := NewGMAC(c, make([]byte, 12))
+	assert.NoError(t, err)
+	assert.Equal(t, IVSizeError(0), mac.ResetIV([]byte{}))
+
+	for _, n := range []int{0, 15, 17, 32} {
+		_, err = NewGHASH(make([]byte, n))
+		assert.Equal(t, KeySizeError(n), err)
+	}
+}
+
+func BenchmarkGHASH(b *testing.B) {
+	h := make([]byte, BlockSize)
+	h[0] = 0x42
+	buf := make([]byte, 1<<16)
+
+	b.Run("Bitwise", func(b *testing.B) {
+		b.SetBytes(int64(len(buf)))
+		for i := 0; i < b.N; i++ {
+			g := newGHASH(h)
+			g.update(buf)
+		}
+	})
+	b.Run("Table", func(b *testing.B) {
+		g, err := NewGHASH(h)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(buf)))
+		for i := 0; i < b.N; i++ {
+			g.Reset()
+			g.Write(buf)
+		}
+	})
+}
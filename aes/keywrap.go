@@ -0,0 +1,192 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// AES Key Wrap (RFC 3394, SP 800-38F KW) encrypts key material under a key
+// encryption key with six passes of a Feistel-like network over 64-bit
+// halves, starting from a fixed integrity check value. Unwrapping runs the
+// network backwards and checks that the value comes out unchanged. Key Wrap
+// with Padding (RFC 5649, KWP) puts the length of the key in the check value
+// so that keys of any length can be wrapped.
+
+const wrapSemiblock = 8
+
+var (
+	// wrapIV is the default initial value of RFC 3394 section 2.2.3.1
+	wrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+	// wrapPadIV is the constant half of the alternative initial value of
+	// RFC 5649 section 3, followed there by the key length
+	wrapPadIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+)
+
+// Wrap wraps key under kek, RFC 3394. key must be a multiple of 8 bytes and
+// at least 16 bytes long, otherwise ErrWrapLength is returned. The result is
+// 8 bytes longer than key.
+func Wrap(kek gocipher.Block, key []byte) ([]byte, error) {
+	if err := checkWrapBlock(kek); err != nil {
+		return nil, err
+	}
+	if len(key) < 2*wrapSemiblock || len(key)%wrapSemiblock != 0 {
+		return nil, ErrWrapLength
+	}
+
+	out := make([]byte, wrapSemiblock+len(key))
+	copy(out, wrapIV)
+	copy(out[wrapSemiblock:], key)
+	wrap(kek, out)
+	return out, nil
+}
+
+// Unwrap unwraps wrapped under kek, RFC 3394. It returns ErrAuthentication if
+// the integrity check fails, which means the wrong key encryption key or a
+// corrupted wrapped key.
+func Unwrap(kek gocipher.Block, wrapped []byte) ([]byte, error) {
+	if err := checkWrapBlock(kek); err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 3*wrapSemiblock || len(wrapped)%wrapSemiblock != 0 {
+		return nil, ErrWrapLength
+	}
+
+	out := append([]byte(nil), wrapped...)
+	unwrap(kek, out)
+
+	if subtle.ConstantTimeCompare(out[:wrapSemiblock], wrapIV) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, ErrAuthentication
+	}
+	return out[wrapSemiblock:], nil
+}
+
+// WrapPad wraps key under kek with padding, RFC 5649. key may be any length
+// from 1 byte to 2^32-1 bytes. The result is key zero padded to a multiple of
+// 8 bytes, plus 8 bytes.
+func WrapPad(kek gocipher.Block, key []byte) ([]byte, error) {
+	if err := checkWrapBlock(kek); err != nil {
+		return nil, err
+	}
+	if len(key) == 0 || uint64(len(key)) > 1<<32-1 {
+		return nil, ErrWrapLength
+	}
+
+	padded := (len(key) + wrapSemiblock - 1) / wrapSemiblock * wrapSemiblock
+	out := make([]byte, wrapSemiblock+padded)
+	copy(out, wrapPadIV)
+	binary.BigEndian.PutUint32(out[4:], uint32(len(key)))
+	copy(out[wrapSemiblock:], key)
+
+	// a key that pads to a single semiblock is encrypted as one block
+	if padded == wrapSemiblock {
+		kek.Encrypt(out, out)
+	} else {
+		wrap(kek, out)
+	}
+	return out, nil
+}
+
+// UnwrapPad unwraps wrapped under kek, RFC 5649. It returns ErrAuthentication
+// if the integrity check fails, without saying which part of the check it was.
+func UnwrapPad(kek gocipher.Block, wrapped []byte) ([]byte, error) {
+	if err := checkWrapBlock(kek); err != nil {
+		return nil, err
+	}
+	if len(wrapped) < 2*wrapSemiblock || len(wrapped)%wrapSemiblock != 0 {
+		return nil, ErrWrapLength
+	}
+
+	out := append([]byte(nil), wrapped...)
+	if len(out) == 2*wrapSemiblock {
+		kek.Decrypt(out, out)
+	} else {
+		unwrap(kek, out)
+	}
+
+	// the constant half of the check value, the length, which must leave
+	// between 0 and 7 bytes of padding, and the padding, which must be zero
+	padded := uint64(len(out) - wrapSemiblock)
+	n := uint64(binary.BigEndian.Uint32(out[4:8]))
+
+	ok := subtle.ConstantTimeCompare(out[:4], wrapPadIV)
+	ok &= ctLess(padded-wrapSemiblock, n)
+	ok &= 1 ^ ctLess(padded, n)
+
+	var nonzero byte
+	for i := uint64(0); i < padded; i++ {
+		// mask selects the bytes at or after position n
+		mask := byte(1^ctLess(i, n)) * 0xff
+		nonzero |= out[wrapSemiblock+i] & mask
+	}
+	ok &= subtle.ConstantTimeByteEq(nonzero, 0)
+
+	if ok != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, ErrAuthentication
+	}
+	return out[wrapSemiblock : wrapSemiblock+n], nil
+}
+
+// ctLess returns 1 if x < y and 0 otherwise, in constant time, for x and y
+// below 2^63
+func ctLess(x, y uint64) int {
+	return int((x - y) >> 63)
+}
+
+func checkWrapBlock(kek gocipher.Block) error {
+	if kek.BlockSize() != BlockSize {
+		return BlockSizeError(kek.BlockSize())
+	}
+	return nil
+}
+
+// wrap is the wrapping function W of RFC 3394 section 2.2.1, in place over
+// the check value and the key in data
+func wrap(kek gocipher.Block, data []byte) {
+	n := len(data)/wrapSemiblock - 1
+	a := data[:wrapSemiblock]
+
+	var b [BlockSize]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			r := data[i*wrapSemiblock : (i+1)*wrapSemiblock]
+
+			copy(b[:wrapSemiblock], a)
+			copy(b[wrapSemiblock:], r)
+			kek.Encrypt(b[:], b[:])
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:wrapSemiblock])^t)
+			copy(r, b[wrapSemiblock:])
+		}
+	}
+}
+
+// unwrap is the inverse W^-1 of wrap, leaving the recovered check value at
+// the start of data
+func unwrap(kek gocipher.Block, data []byte) {
+	n := len(data)/wrapSemiblock - 1
+	a := data[:wrapSemiblock]
+
+	var b [BlockSize]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			r := data[i*wrapSemiblock : (i+1)*wrapSemiblock]
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:wrapSemiblock], binary.BigEndian.Uint64(a)^t)
+			copy(b[wrapSemiblock:], r)
+			kek.Decrypt(b[:], b[:])
+
+			copy(a, b[:wrapSemiblock])
+			copy(r, b[wrapSemiblock:])
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 3394 section 4, every combination of key encryption key and key data
+// size
+var wrapTests = []struct {
+	kek     string
+	key     string
+	wrapped string
+}{
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"00112233445566778899aabbccddeeff",
+		"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f1011121314151617",
+		"00112233445566778899aabbccddeeff",
+		"96778b25ae6ca435f92b5b97c050aed2468ab8a17ad84e5d",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"00112233445566778899aabbccddeeff",
+		"64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f1011121314151617",
+		"00112233445566778899aabbccddeeff0001020304050607",
+		"031d33264e15d33268f24ec260743edce1c6c7ddee725a936ba814915c6762d2",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"00112233445566778899aabbccddeeff0001020304050607",
+		"a8f9bc1612c68b3ff6e6f4fbe30e71e4769c8b80a32cb8958cd5d17d6b254da1",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+		"28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+	},
+}
+
+// RFC 5649 section 6, followed by keys on either side of a semiblock
+// boundary, checked against OpenSSL's id-aes128-wrap-pad and
+// id-aes256-wrap-pad
+var wrapPadTests = []struct {
+	kek     string
+	key     string
+	wrapped string
+}{
+	{
+		"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+		"c37b7e6492584340bed12207808941155068f738",
+		"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+	},
+	{
+		"5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8",
+		"466f7250617369",
+		"afbeb0f07dfbf5419200f2ccb50bb24f",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"01",
+		"354adcce4f3b9a3ecc942d83cf9f216f",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"0011223344556677",
+		"23ea99084e592c2f29f496536c00d5af",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"001122334455667788",
+		"b4bd457489f2aabdbebf0db46e64e195af069b81a9f3d20d",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"00112233445566778899aabbccddeeff",
+		"2cef0c9e30de26016c230cb78bc60d51b1fe083ba0c79cd5",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"01",
+		"57bd956ca41f9470203c090c613f5bc5",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"0011223344556677",
+		"2bf5af5b28f4cb67cd3e1b1f9ac4049a",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"001122334455667788",
+		"6216054b046d66cd763f4fc3f08152c18d1cb013d3739d4c",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"00112233445566778899aabbccddeeff",
+		"afc860015ffe2d75bedf43c444fe58f4ad9d89c4ec71e23b",
+	},
+}
+
+func TestWrap(t *testing.T) {
+	for _, test := range wrapTests {
+		c, err := NewCipher(decodeHex(t, test.kek))
+		assert.NoError(t, err)
+
+		key := decodeHex(t, test.key)
+		wrapped := decodeHex(t, test.wrapped)
+
+		out, err := Wrap(c, key)
+		assert.NoError(t, err)
+		assert.Equal(t, wrapped, out, test.kek)
+
+		out, err = Unwrap(c, wrapped)
+		assert.NoError(t, err)
+		assert.Equal(t, key, out, test.kek)
+	}
+}
+
+func TestWrapPad(t *testing.T) {
+	for _, test := range wrapPadTests {
+		c, err := NewCipher(decodeHex(t, test.kek))
+		assert.NoError(t, err)
+
+		key := decodeHex(t, test.key)
+		wrapped := decodeHex(t, test.wrapped)
+
+		out, err := WrapPad(c, key)
+		assert.NoError(t, err)
+		assert.Equal(t, wrapped, out, test.key)
+
+		out, err = UnwrapPad(c, wrapped)
+		assert.NoError(t, err)
+		assert.Equal(t, key, out, test.key)
+	}
+}
+
+func TestWrapRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	kek := make([]byte, 32)
+	rng.Read(kek)
+	c, err := NewCipherBackend(kek, TTable)
+	assert.NoError(t, err)
+
+	for n := 1; n <= 80; n++ {
+		key := make([]byte, n)
+		rng.Read(key)
+
+		if n >= 16 && n%8 == 0 {
+			wrapped, err := Wrap(c, key)
+			assert.NoError(t, err)
+			unwrapped, err := Unwrap(c, wrapped)
+			assert.NoError(t, err)
+			assert.Equal(t, key, unwrapped, "length %d", n)
+		}
+
+		wrapped, err := WrapPad(c, key)
+		assert.NoError(t, err)
+		assert.Equal(t, (n+7)/8*8+8, len(wrapped), "length %d", n)
+		unwrapped, err := UnwrapPad(c, wrapped)
+		assert.NoError(t, err)
+		assert.Equal(t, key, unwrapped, "length %d", n)
+	}
+}
+
+func TestWrapTampering(t *testing.T) {
+	test := wrapTests[5]
+	c, err := NewCipher(decodeHex(t, test.kek))
+	assert.NoError(t, err)
+	wrapped := decodeHex(t, test.wrapped)
+
+	for i := range wrapped {
+		tampered := append([]byte(nil), wrapped...)
+		tampered[i] ^= 0x01
+
+		out, err := Unwrap(c, tampered)
+		assert.Equal(t, ErrAuthentication, err, "byte %d", i)
+		assert.Nil(t, out)
+	}
+
+	// a key wrapped with padding is not a valid plain wrapped key, and the
+	// other way around
+	padded, err := WrapPad(c, decodeHex(t, test.key))
+	assert.NoError(t, err)
+	_, err = Unwrap(c, padded)
+	assert.Equal(t, ErrAuthentication, err)
+	_, err = UnwrapPad(c, wrapped)
+	assert.Equal(t, ErrAuthentication, err)
+}
+
+func TestWrapPadTampering(t *testing.T) {
+	for _, test := range wrapPadTests[:2] {
+		c, err := NewCipher(decodeHex(t, test.kek))
+		assert.NoError(t, err)
+		wrapped := decodeHex(t, test.wrapped)
+
+		for i := range wrapped {
+			tampered := append([]byte(nil), wrapped...)
+			tampered[i] ^= 0x01
+
+			out, err := UnwrapPad(c, tampered)
+			assert.Equal(t, ErrAuthentication, err, "byte %d", i)
+			assert.Nil(t, out)
+		}
+	}
+}
+
+// decryptRecorder keeps the destination of every block it decrypts
+type decryptRecorder struct {
+	gocipher.Block
+	dst [][]byte
+}
+
+func (d *decryptRecorder) Decrypt(dst, src []byte) {
+	d.Block.Decrypt(dst, src)
+	d.dst = append(d.dst, dst)
+}
+
+func TestUnwrapClearsOutput(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, wrapTests[0].kek))
+	assert.NoError(t, err)
+	wrapped := decodeHex(t, wrapTests[0].wrapped)
+	wrapped[len(wrapped)-1] ^= 0x01
+	out, err := Unwrap(c, wrapped)
+	assert.Equal(t, ErrAuthentication, err)
+	assert.Nil(t, out)
+
+	for _, test := range wrapPadTests[:2] {
+		c, err := NewCipher(decodeHex(t, test.kek))
+		assert.NoError(t, err)
+		wrapped := decodeHex(t, test.wrapped)
+		wrapped[len(wrapped)-1] ^= 0x01
+
+		rec := &decryptRecorder{Block: c}
+		out, err := UnwrapPad(rec, wrapped)
+		assert.Equal(t, ErrAuthentication, err, test.wrapped)
+		assert.Nil(t, out)
+
+		// a single semiblock key is decrypted straight into the output,
+		// which must not keep the candidate key
+		if len(wrapped) == 2*wrapSemiblock {
+			assert.Len(t, rec.dst, 1)
+			assert.Equal(t, make([]byte, len(wrapped)), rec.dst[0])
+		}
+	}
+}
+
+// TestWrapPadCheck wraps check values and padding directly to exercise each
+// part of the check of RFC 5649 section 3 on a 16 byte padded key
+func TestWrapPadCheck(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		in    string
+		valid bool
+	}{
+		{"a65959a600000010" + "ffffffffffffffffffffffffffffffff", true},
+		{"a65959a600000009" + "ff00000000000000ffffffffffffffff", false},
+		{"a65959a600000009" + "ffffffffffffffffff00000000000000", true},
+		{"a65959a60000000c" + "ffffffffffffffffffffffff00000001", false},
+		{"a65959a600000008" + "ffffffffffffffff0000000000000000", false},
+		{"a65959a600000011" + "ffffffffffffffffffffffffffffffff", false},
+		{"a65959a6ffffffff" + "ffffffffffffffffffffffffffffffff", false},
+		{"a65959a600000000" + "00000000000000000000000000000000", false},
+		{"a6a6a6a600000010" + "ffffffffffffffffffffffffffffffff", false},
+	} {
+		in := decodeHex(t, test.in)
+		n := int(in[7])
+		wrap(c, in)
+
+		out, err := UnwrapPad(c, in)
+		if test.valid {
+			assert.NoError(t, err, test.in)
+			assert.Equal(t, decodeHex(t, test.in)[8:8+n], out)
+		} else {
+			assert.Equal(t, ErrAuthentication, err, test.in)
+			assert.Nil(t, out)
+		}
+	}
+}
+
+func TestWrapErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	for _, n := range []int{0, 8, 15, 17, 23} {
+		_, err = Wrap(c, make([]byte, n))
+		assert.Equal(t, ErrWrapLength, err, "length %d", n)
+	}
+	for _, n := range []int{0, 8, 16, 23, 25} {
+		_, err = Unwrap(c, make([]byte, n))
+		assert.Equal(t, ErrWrapLength, err, "length %d", n)
+	}
+
+	_, err = WrapPad(c, nil)
+	assert.Equal(t, ErrWrapLength, err)
+	for _, n := range []int{0, 8, 15, 17} {
+		_, err = UnwrapPad(c, make([]byte, n))
+		assert.Equal(t, ErrWrapLength, err, "length %d", n)
+	}
+}
@@ -0,0 +1,235 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/subtle"
+)
+
+// Offset Codebook mode, version 3 (RFC 7253) encrypts each block between
+// two XORs of an offset and sums the plaintext blocks into a checksum that
+// is encrypted into the tag, so a message costs about one block cipher call
+// per block. Successive offsets differ by L_ntz(i), where the L values are
+// repeated doublings of the encryption of the zero block and ntz(i) is the
+// number of trailing zero bits of the block index.
+
+const (
+	ocbMaxNonceSize = 15
+
+	// ocbLTableSize L values cover messages of up to 2^64 blocks
+	ocbLTableSize = 64
+)
+
+type ocb struct {
+	b         gocipher.Block
+	nonceSize int
+	tagSize   int
+
+	lStar   []byte
+	lDollar []byte
+	l       [][]byte
+}
+
+var _ gocipher.AEAD = (*ocb)(nil)
+
+// NewOCB returns b wrapped in OCB3. nonceSize must be between 1 and 15 bytes,
+// RFC 7253 recommending 12, and tagSize 8, 12 or 16 bytes. b must have a 16
+// byte block size.
+func NewOCB(b gocipher.Block, nonceSize, tagSize int) (gocipher.AEAD, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, BlockSizeError(b.BlockSize())
+	}
+	if nonceSize < 1 || nonceSize > ocbMaxNonceSize {
+		return nil, IVSizeError(nonceSize)
+	}
+	switch tagSize {
+	case 8, 12, 16:
+	default:
+		return nil, TagSizeError(tagSize)
+	}
+
+	o := &ocb{b: b, nonceSize: nonceSize, tagSize: tagSize}
+
+	o.lStar = make([]byte, BlockSize)
+	b.Encrypt(o.lStar, o.lStar)
+
+	o.lDollar = append([]byte(nil), o.lStar...)
+	double(o.lDollar)
+
+	o.l = make([][]byte, ocbLTableSize)
+	prev := o.lDollar
+	for i := range o.l {
+		o.l[i] = append([]byte(nil), prev...)
+		double(o.l[i])
+		prev = o.l[i]
+	}
+
+	return o, nil
+}
+
+func (o *ocb) NonceSize() int {
+	return o.nonceSize
+}
+
+func (o *ocb) Overhead() int {
+	return o.tagSize
+}
+
+func (o *ocb) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != o.nonceSize {
+		panic("aes: incorrect nonce length given to OCB")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+o.tagSize)
+
+	var tag [BlockSize]byte
+	o.crypt(out, tag[:], nonce, plaintext, additionalData, false)
+	copy(out[len(plaintext):], tag[:o.tagSize])
+
+	return ret
+}
+
+func (o *ocb) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != o.nonceSize {
+		panic("aes: incorrect nonce length given to OCB")
+	}
+	if len(ciphertext) < o.tagSize {
+		return nil, ErrAuthentication
+	}
+
+	var tag [BlockSize]byte
+	copy(tag[:], ciphertext[len(ciphertext)-o.tagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-o.tagSize]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+
+	var expected [BlockSize]byte
+	o.crypt(out, expected[:], nonce, ciphertext, additionalData, true)
+
+	if subtle.ConstantTimeCompare(expected[:o.tagSize], tag[:o.tagSize]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, ErrAuthentication
+	}
+
+	return ret, nil
+}
+
+// crypt encrypts or decrypts src into dst and writes the full 16 byte tag
+// over the plaintext and additionalData to tag
+func (o *ocb) crypt(dst, tag, nonce, src, additionalData []byte, decrypt bool) {
+	offset := o.initialOffset(nonce)
+
+	var checksum, tmp [BlockSize]byte
+	i := 1
+	for ; len(src) >= BlockSize; i++ {
+		xorBytes(offset, offset, o.l[ntz(i)])
+
+		xorBytes(tmp[:], src[:BlockSize], offset)
+		if decrypt {
+			o.b.Decrypt(tmp[:], tmp[:])
+		} else {
+			xorBytes(checksum[:], checksum[:], src[:BlockSize])
+			o.b.Encrypt(tmp[:], tmp[:])
+		}
+		xorBytes(dst[:BlockSize], tmp[:], offset)
+
+		if decrypt {
+			xorBytes(checksum[:], checksum[:], dst[:BlockSize])
+		}
+
+		src = src[BlockSize:]
+		dst = dst[BlockSize:]
+	}
+
+	// a final partial block is XORed with the encryption of its offset and
+	// enters the checksum padded with a single one bit
+	if len(src) > 0 {
+		xorBytes(offset, offset, o.lStar)
+
+		var pad [BlockSize]byte
+		o.b.Encrypt(pad[:], offset)
+
+		if !decrypt {
+			xorBytes(checksum[:], checksum[:], src)
+		}
+		xorBytes(dst, src, pad[:])
+		if decrypt {
+			xorBytes(checksum[:], checksum[:], dst[:len(src)])
+		}
+		checksum[len(src)] ^= 0x80
+	}
+
+	xorBytes(tmp[:], checksum[:], offset)
+	xorBytes(tmp[:], tmp[:], o.lDollar)
+	o.b.Encrypt(tag, tmp[:])
+
+	o.hash(tmp[:], additionalData)
+	xorBytes(tag, tag, tmp[:])
+}
+
+// initialOffset computes Offset_0 from the nonce, RFC 7253 section 4.2. The
+// last six bits of the formatted nonce select where a 128 bit window starts
+// in a 192 bit stretch of the encryption of the rest of it.
+func (o *ocb) initialOffset(nonce []byte) []byte {
+	var n [BlockSize]byte
+	n[0] = byte(o.tagSize*8%128) << 1
+	n[BlockSize-1-len(nonce)] |= 1
+	copy(n[BlockSize-len(nonce):], nonce)
+
+	bottom := uint(n[BlockSize-1] & 0x3f)
+	n[BlockSize-1] &^= 0x3f
+
+	var stretch [BlockSize + 8]byte
+	o.b.Encrypt(stretch[:BlockSize], n[:])
+	for i := 0; i < 8; i++ {
+		stretch[BlockSize+i] = stretch[i] ^ stretch[i+1]
+	}
+
+	// Offset_0 is bits bottom to bottom+127 of the stretch
+	offset := make([]byte, BlockSize)
+	byteShift, bitShift := bottom/8, bottom%8
+	for i := range offset {
+		offset[i] = stretch[uint(i)+byteShift] << bitShift
+		if bitShift != 0 {
+			offset[i] |= stretch[uint(i)+byteShift+1] >> (8 - bitShift)
+		}
+	}
+	return offset
+}
+
+// hash writes HASH(K, A) of RFC 7253 section 4.1 to sum
+func (o *ocb) hash(sum, additionalData []byte) {
+	for i := range sum {
+		sum[i] = 0
+	}
+
+	var offset, tmp [BlockSize]byte
+	for i := 1; len(additionalData) >= BlockSize; i++ {
+		xorBytes(offset[:], offset[:], o.l[ntz(i)])
+		xorBytes(tmp[:], additionalData[:BlockSize], offset[:])
+		o.b.Encrypt(tmp[:], tmp[:])
+		xorBytes(sum, sum, tmp[:])
+		additionalData = additionalData[BlockSize:]
+	}
+
+	if len(additionalData) > 0 {
+		xorBytes(offset[:], offset[:], o.lStar)
+		tmp = [BlockSize]byte{}
+		copy(tmp[:], additionalData)
+		tmp[len(additionalData)] = 0x80
+		xorBytes(tmp[:], tmp[:], offset[:])
+		o.b.Encrypt(tmp[:], tmp[:])
+		xorBytes(sum, sum, tmp[:])
+	}
+}
+
+// ntz returns the number of trailing zero bits of i, which is not zero
+func ntz(i int) int {
+	n := 0
+	for i&1 == 0 {
+		i >>= 1
+		n++
+	}
+	return n
+}
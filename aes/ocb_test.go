@@ -0,0 +1,272 @@
+package aes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 7253 appendix A, all under the key 000102...0f with a 128 bit tag. The
+// ciphertext includes the tag.
+var ocbTests = []struct {
+	nonce      string
+	ad         string
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"bbaa99887766554433221100",
+		"",
+		"",
+		"785407bfffc8ad9edcc5520ac9111ee6",
+	},
+	{
+		"bbaa99887766554433221101",
+		"0001020304050607",
+		"0001020304050607",
+		"6820b3657b6f615a5725bda0d3b4eb3a257c9af1f8f03009",
+	},
+	{
+		"bbaa99887766554433221102",
+		"0001020304050607",
+		"",
+		"81017f8203f081277152fade694a0a00",
+	},
+	{
+		"bbaa99887766554433221103",
+		"",
+		"0001020304050607",
+		"45dd69f8f5aae72414054cd1f35d82760b2cd00d2f99bfa9",
+	},
+	{
+		"bbaa99887766554433221104",
+		"000102030405060708090a0b0c0d0e0f",
+		"000102030405060708090a0b0c0d0e0f",
+		"571d535b60b277188be5147170a9a22c3ad7a4ff3835b8c5701c1ccec8fc3358",
+	},
+	{
+		"bbaa99887766554433221105",
+		"000102030405060708090a0b0c0d0e0f",
+		"",
+		"8cf761b6902ef764462ad86498ca6b97",
+	},
+	{
+		"bbaa99887766554433221106",
+		"",
+		"000102030405060708090a0b0c0d0e0f",
+		"5ce88ec2e0692706a915c00aeb8b2396f40e1c743f52436bdf06d8fa1eca343d",
+	},
+	{
+		"bbaa99887766554433221107",
+		"000102030405060708090a0b0c0d0e0f1011121314151617",
+		"000102030405060708090a0b0c0d0e0f1011121314151617",
+		"1ca2207308c87c010756104d8840ce1952f09673a448a122c92c62241051f57356d7f3c90bb0e07f",
+	},
+	{
+		"bbaa99887766554433221108",
+		"000102030405060708090a0b0c0d0e0f1011121314151617",
+		"",
+		"6dc225a071fc1b9f7c69f93b0f1e10de",
+	},
+	{
+		"bbaa99887766554433221109",
+		"",
+		"000102030405060708090a0b0c0d0e0f1011121314151617",
+		"221bd0de7fa6fe993eccd769460a0af2d6cded0c395b1c3ce725f32494b9f914d85c0b1eb38357ff",
+	},
+	{
+		"bbaa9988776655443322110a",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"bd6f6c496201c69296c11efd138a467abd3c707924b964deaffc40319af5a48540fbba186c5553c68ad9f592a79a4240",
+	},
+	{
+		"bbaa9988776655443322110b",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"",
+		"fe80690bee8a485d11f32965bc9d2a32",
+	},
+	{
+		"bbaa9988776655443322110c",
+		"",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"2942bfc773bda23cabc6acfd9bfd5835bd300f0973792ef46040c53f1432bcdfb5e1dde3bc18a5f840b52e653444d5df",
+	},
+	{
+		"bbaa9988776655443322110d",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627",
+		"d5ca91748410c1751ff8a2f618255b68a0a12e093ff454606e59f9c1d0ddc54b65e8628e568bad7aed07ba06a4a69483a7035490c5769e60",
+	},
+	{
+		"bbaa9988776655443322110e",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627",
+		"",
+		"c5cd9d1850c141e358649994ee701b68",
+	},
+	{
+		"bbaa9988776655443322110f",
+		"",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627",
+		"4412923493c57d5de0d700f753cce0d1d2d95060122e9f15a5ddbfc5787e50b5cc55ee507bcb084e479ad363ac366b95a98ca5f3000b1479",
+	},
+}
+
+func TestOCB(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	assert.NoError(t, err)
+	aead, err := NewOCB(c, 12, 16)
+	assert.NoError(t, err)
+
+	for _, test := range ocbTests {
+		nonce := decodeHex(t, test.nonce)
+		ad := decodeHex(t, test.ad)
+		plaintext := decodeHex(t, test.plaintext)
+		ciphertext := decodeHex(t, test.ciphertext)
+
+		assert.Equal(t, ciphertext, aead.Seal(nil, nonce, plaintext, ad), test.nonce)
+
+		opened, err := aead.Open(nil, nonce, ciphertext, ad)
+		assert.NoError(t, err, test.nonce)
+		assert.True(t, bytes.Equal(plaintext, opened), test.nonce)
+	}
+}
+
+func TestOCBTagSize96(t *testing.T) {
+	// the 96 bit tag example of RFC 7253 appendix A
+	c, err := NewCipher(decodeHex(t, "0f0e0d0c0b0a09080706050403020100"))
+	assert.NoError(t, err)
+	aead, err := NewOCB(c, 12, 12)
+	assert.NoError(t, err)
+
+	nonce := decodeHex(t, "bbaa9988776655443322110d")
+	data := decodeHex(t, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627")
+	ciphertext := decodeHex(t, "1792a4e31e0755fb03e31b22116e6c2ddf9efd6e33d536f1a0124b0a55bae884ed93481529c76b6ad0c515f4d1cdd4fdac4f02aa")
+
+	assert.Equal(t, ciphertext, aead.Seal(nil, nonce, data, data))
+
+	opened, err := aead.Open(nil, nonce, ciphertext, data)
+	assert.NoError(t, err)
+	assert.Equal(t, data, opened)
+}
+
+// The iterated test of RFC 7253 appendix A, which covers every key and tag
+// size over messages of 0 to 127 blocks
+var ocbIteratedTests = []struct {
+	keyLen int
+	tagLen int
+	output string
+}{
+	{128, 128, "67e944d23256c5e0b6c61fa22fdf1ea2"},
+	{192, 128, "f673f2c3e7174aae7bae986ca9f29e17"},
+	{256, 128, "d90eb8e9c977c88b79dd793d7ffa161c"},
+	{128, 96, "77a3d8e73589158d25d01209"},
+	{192, 96, "05d56ead2752c86be6932c5e"},
+	{256, 96, "5458359ac23b0cba9e6330dd"},
+	{128, 64, "192c9b7bd90ba06a"},
+	{192, 64, "0066bc6e0ef34e24"},
+	{256, 64, "7d4ea5d445501cbe"},
+}
+
+func TestOCBIterated(t *testing.T) {
+	for _, test := range ocbIteratedTests {
+		key := make([]byte, test.keyLen/8)
+		key[len(key)-1] = byte(test.tagLen)
+		c, err := NewCipherBackend(key, TTable)
+		assert.NoError(t, err)
+		aead, err := NewOCB(c, 12, test.tagLen/8)
+		assert.NoError(t, err)
+
+		nonce := func(n int) []byte {
+			b := make([]byte, 12)
+			binary.BigEndian.PutUint32(b[8:], uint32(n))
+			return b
+		}
+
+		var out []byte
+		for i := 0; i < 128; i++ {
+			s := make([]byte, i)
+			out = aead.Seal(out, nonce(3*i+1), s, s)
+			out = aead.Seal(out, nonce(3*i+2), s, nil)
+			out = aead.Seal(out, nonce(3*i+3), nil, s)
+		}
+
+		assert.Equal(t, decodeHex(t, test.output), aead.Seal(nil, nonce(385), nil, out), "key %d tag %d", test.keyLen, test.tagLen)
+	}
+}
+
+func TestOCBRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	key := make([]byte, 16)
+	rng.Read(key)
+	c, err := NewCipherBackend(key, TTable)
+	assert.NoError(t, err)
+
+	for _, nonceSize := range []int{1, 12, 15} {
+		aead, err := NewOCB(c, nonceSize, 16)
+		assert.NoError(t, err)
+
+		nonce := make([]byte, nonceSize)
+		rng.Read(nonce)
+		for n := 0; n <= 64; n++ {
+			plaintext := make([]byte, n)
+			rng.Read(plaintext)
+
+			// in place, over the plaintext's own storage
+			buf := make([]byte, n, n+16)
+			copy(buf, plaintext)
+			sealed := aead.Seal(buf[:0], nonce, buf, nil)
+
+			opened, err := aead.Open(sealed[:0], nonce, sealed, nil)
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(plaintext, opened), "nonce %d length %d", nonceSize, n)
+		}
+	}
+}
+
+func TestOCBTampering(t *testing.T) {
+	test := ocbTests[13]
+	c, err := NewCipher(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	assert.NoError(t, err)
+	aead, err := NewOCB(c, 12, 16)
+	assert.NoError(t, err)
+
+	nonce := decodeHex(t, test.nonce)
+	ad := decodeHex(t, test.ad)
+	sealed := decodeHex(t, test.ciphertext)
+
+	for i := range sealed {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x01
+
+		opened, err := aead.Open(nil, nonce, tampered, ad)
+		assert.Equal(t, ErrAuthentication, err, "byte %d", i)
+		assert.Nil(t, opened)
+	}
+
+	_, err = aead.Open(nil, nonce, sealed, ad[1:])
+	assert.Equal(t, ErrAuthentication, err)
+	_, err = aead.Open(nil, nonce, sealed[:15], ad)
+	assert.Equal(t, ErrAuthentication, err)
+}
+
+func TestOCBErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	for _, nonceSize := range []int{0, 16} {
+		_, err = NewOCB(c, nonceSize, 16)
+		assert.Equal(t, IVSizeError(nonceSize), err)
+	}
+	for _, tagSize := range []int{0, 4, 10, 15, 17} {
+		_, err = NewOCB(c, 12, tagSize)
+		assert.Equal(t, TagSizeError(tagSize), err)
+	}
+
+	aead, err := NewOCB(c, 12, 16)
+	assert.NoError(t, err)
+	assert.Panics(t, func() { aead.Seal(nil, make([]byte, 8), nil, nil) })
+}
@@ -0,0 +1,47 @@
+package aes
+
+import gocipher "crypto/cipher"
+
+// Output Feedback mode (SP 800-38A section 6.4) generates the keystream by
+// encrypting the IV over and over, each output block becoming the next input.
+// The keystream depends only on the key and IV, never on the message.
+
+type ofb struct {
+	b    gocipher.Block
+	ks   []byte
+	used int
+}
+
+// NewOFB returns a crypto/cipher.Stream which encrypts or decrypts in Output
+// Feedback mode, starting from iv. iv must be one block long, otherwise an
+// IVSizeError is returned.
+func NewOFB(b gocipher.Block, iv []byte) (gocipher.Stream, error) {
+	if len(iv) != b.BlockSize() {
+		return nil, IVSizeError(len(iv))
+	}
+
+	x := &ofb{
+		b:  b,
+		ks: append([]byte(nil), iv...),
+	}
+	x.used = len(x.ks)
+	return x, nil
+}
+
+func (x *ofb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("aes: output smaller than input")
+	}
+
+	for len(src) > 0 {
+		if x.used == len(x.ks) {
+			x.b.Encrypt(x.ks, x.ks)
+			x.used = 0
+		}
+
+		n := xorBytes(dst, src, x.ks[x.used:])
+		x.used += n
+		src = src[n:]
+		dst = dst[n:]
+	}
+}
@@ -0,0 +1,94 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SP 800-38A section F.4
+var ofbTests = []struct {
+	name       string
+	key        string
+	iv         string
+	plaintext  string
+	ciphertext string
+}{
+	{
+		"F.4.1 OFB-AES128",
+		"2b7e151628aed2a6abf7158809cf4f3c",
+		"000102030405060708090a0b0c0d0e0f",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"3b3fd92eb72dad20333449f8e83cfb4a7789508d16918f03f53c52dac54ed8259740051e9c5fecf64344f7a82260edcc304c6528f659c77866a510d9c1d6ae5e",
+	},
+	{
+		"F.4.3 OFB-AES192",
+		"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b",
+		"000102030405060708090a0b0c0d0e0f",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"cdc80d6fddf18cab34c25909c99a4174fcc28b8d4c63837c09e81700c11004018d9a9aeac0f6596f559c6d4daf59a5f26d9f200857ca6c3e9cac524bd9acc92a",
+	},
+	{
+		"F.4.5 OFB-AES256",
+		"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		"000102030405060708090a0b0c0d0e0f",
+		"6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710",
+		"dc7e84bfda79164b7ecd8486985d38604febdc6740d20b3ac88f6ad82a4fb08d71ab47a086e86eedf39d1c5bba97c4080126141d67f37be8538f5a8be740e484",
+	},
+}
+
+func TestOFB(t *testing.T) {
+	for _, test := range ofbTests {
+		c, err := NewCipherBackend(decodeHex(t, test.key), TTable)
+		assert.NoError(t, err)
+
+		iv := decodeHex(t, test.iv)
+		plaintext := decodeHex(t, test.plaintext)
+		ciphertext := decodeHex(t, test.ciphertext)
+
+		stream, err := NewOFB(c, iv)
+		assert.NoError(t, err)
+
+		// in uneven pieces, to cross block boundaries mid call
+		out := make([]byte, len(plaintext))
+		for _, piece := range [][2]int{{0, 5}, {5, 16}, {16, 40}, {40, 64}} {
+			stream.XORKeyStream(out[piece[0]:piece[1]], plaintext[piece[0]:piece[1]])
+		}
+		assert.Equal(t, ciphertext, out, test.name)
+
+		// and decrypt in place
+		stream, err = NewOFB(c, iv)
+		assert.NoError(t, err)
+		stream.XORKeyStream(out, out)
+		assert.Equal(t, plaintext, out, test.name)
+	}
+}
+
+func TestOFBStdlib(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, ofbTests[0].key))
+	assert.NoError(t, err)
+	iv := decodeHex(t, ofbTests[0].iv)
+
+	rng := rand.New(rand.NewSource(465))
+	plaintext := make([]byte, 1000)
+	rng.Read(plaintext)
+
+	expected := make([]byte, len(plaintext))
+	gocipher.NewOFB(c, iv).XORKeyStream(expected, plaintext)
+
+	stream, err := NewOFB(c, iv)
+	assert.NoError(t, err)
+	out := make([]byte, len(plaintext))
+	stream.XORKeyStream(out, plaintext)
+	assert.Equal(t, expected, out)
+}
+
+func TestOFBErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	_, err = NewOFB(c, make([]byte, 15))
+	assert.Equal(t, IVSizeError(15), err)
+}
@@ -0,0 +1,69 @@
+package aes
+
+import "crypto/subtle"
+
+// Padding extends a message to a whole number of blocks before encryption and
+// removes the extension again after decryption
+type Padding interface {
+	// Pad returns data followed by its padding
+	Pad(data []byte, blockSize int) []byte
+	// Unpad returns data without its padding, or ErrInvalidPadding
+	Unpad(data []byte, blockSize int) ([]byte, error)
+}
+
+// PKCS7 is the padding of RFC 5652 section 6.3. It appends n bytes of value n,
+// adding a whole block when the message is already a multiple of the block
+// size, so it can always be removed unambiguously.
+type PKCS7 struct{}
+
+// Pad appends between 1 and blockSize bytes of padding to data
+func (PKCS7) Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+
+	out := make([]byte, len(data)+n)
+	copy(out, data)
+	for i := len(data); i < len(out); i++ {
+		out[i] = byte(n)
+	}
+	return out
+}
+
+// Unpad checks and strips the padding added by Pad. All of the last blockSize
+// bytes are examined, with no branch on their values, so the time taken does
+// not reveal the padding length or where the padding went wrong.
+func (PKCS7) Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, ErrNotFullBlocks
+	}
+
+	last := data[len(data)-blockSize:]
+	n := last[blockSize-1]
+	good := subtle.ConstantTimeLessOrEq(1, int(n)) & subtle.ConstantTimeLessOrEq(int(n), blockSize)
+
+	// the byte i from the end must equal n if it is one of the n padding
+	// bytes, and may be anything otherwise
+	for i := 0; i < blockSize; i++ {
+		padding := subtle.ConstantTimeLessOrEq(i+1, int(n))
+		match := subtle.ConstantTimeByteEq(last[blockSize-1-i], n)
+		good &= subtle.ConstantTimeSelect(padding, match, 1)
+	}
+
+	if good != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return data[:len(data)-int(n)], nil
+}
+
+// NoPadding leaves messages untouched, so they must already be a whole number
+// of blocks
+type NoPadding struct{}
+
+// Pad returns data as it is
+func (NoPadding) Pad(data []byte, blockSize int) []byte {
+	return data
+}
+
+// Unpad returns data as it is
+func (NoPadding) Unpad(data []byte, blockSize int) ([]byte, error) {
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package aes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPKCS7Pad(t *testing.T) {
+	assert.Equal(t, []byte{0x61, 0x62, 0x63, 0x05, 0x05, 0x05, 0x05, 0x05}, PKCS7{}.Pad([]byte("abc"), 8))
+	assert.Equal(t, []byte{0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08}, PKCS7{}.Pad(nil, 8))
+	assert.Equal(t, append([]byte("abcdefgh"), 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08), PKCS7{}.Pad([]byte("abcdefgh"), 8))
+}
+
+func TestPKCS7Unpad(t *testing.T) {
+	out, err := PKCS7{}.Unpad([]byte{0x61, 0x62, 0x63, 0x05, 0x05, 0x05, 0x05, 0x05}, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("abc"), out)
+
+	out, err = PKCS7{}.Unpad([]byte{0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08}, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, out)
+
+	bad := [][]byte{
+		{0x61, 0x62, 0x63, 0x05, 0x05, 0x04, 0x05, 0x05}, // inconsistent padding bytes
+		{0x61, 0x62, 0x63, 0x05, 0x05, 0x05, 0x05, 0x00}, // zero is never valid
+		{0x61, 0x62, 0x63, 0x05, 0x05, 0x05, 0x05, 0x09}, // longer than a block
+		{0x61, 0x62, 0x63, 0x04, 0x05, 0x05, 0x05, 0x05}, // first padding byte wrong
+		{0x07, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08}, // whole block, first byte wrong
+	}
+	for _, b := range bad {
+		_, err := PKCS7{}.Unpad(b, 8)
+		assert.Equal(t, ErrInvalidPadding, err, "%x", b)
+	}
+
+	_, err = PKCS7{}.Unpad([]byte{0x01, 0x01, 0x01}, 8)
+	assert.Equal(t, ErrNotFullBlocks, err)
+
+	_, err = PKCS7{}.Unpad(nil, 8)
+	assert.Equal(t, ErrNotFullBlocks, err)
+}
@@ -0,0 +1,209 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"hash"
+	"math/bits"
+	"sync"
+)
+
+// PMAC (Rogaway, "Efficient Instantiations of Tweakable Blockciphers and
+// Refinements to Modes OCB and PMAC", PMAC1) encrypts every block but the
+// last XORed with an offset, sums the results and encrypts the sum together
+// with the last block into the tag. The offsets follow OCB's: block i uses
+// the XOR of L(k) over the bits k set in the Gray code of i, so any block's
+// offset can be found directly and the blocks can be split among goroutines
+// whose sums are XORed together at the end.
+
+const (
+	// pmacLTableSize L values cover messages of up to 2^64 blocks
+	pmacLTableSize = 64
+
+	// pmacParallelBlocks is the fewest blocks worth handing to each worker;
+	// shorter writes are processed serially
+	pmacParallelBlocks = 256
+)
+
+// PMAC is a PMAC computation in progress. It implements hash.Hash.
+type PMAC struct {
+	b       gocipher.Block
+	workers int
+
+	l    [][]byte
+	lInv []byte
+
+	// offset and sigma are the offset and sum after the first i blocks. The
+	// block in buf is held back until more data shows it is not the last.
+	offset [BlockSize]byte
+	sigma  [BlockSize]byte
+	i      uint64
+	buf    [BlockSize]byte
+	n      int
+}
+
+var _ hash.Hash = (*PMAC)(nil)
+
+// NewPMAC returns a PMAC under b that processes the message serially. b must
+// have a 16 byte block size.
+func NewPMAC(b gocipher.Block) (*PMAC, error) {
+	return NewPMACParallel(b, 1)
+}
+
+// NewPMACParallel returns a PMAC under b that splits long writes among up to
+// workers goroutines. The tag is the same as a serial PMAC's. b's Encrypt
+// must be safe to call concurrently, which it is for a Cipher with no Tracer.
+// workers must be at least 1, otherwise ErrPMACWorkers is returned.
+func NewPMACParallel(b gocipher.Block, workers int) (*PMAC, error) {
+	if b.BlockSize() != BlockSize {
+		return nil, BlockSizeError(b.BlockSize())
+	}
+	if workers < 1 {
+		return nil, ErrPMACWorkers
+	}
+
+	p := &PMAC{b: b, workers: workers}
+
+	p.l = make([][]byte, pmacLTableSize)
+	p.l[0] = make([]byte, BlockSize)
+	b.Encrypt(p.l[0], p.l[0])
+	for i := 1; i < len(p.l); i++ {
+		p.l[i] = append([]byte(nil), p.l[i-1]...)
+		double(p.l[i])
+	}
+
+	p.lInv = append([]byte(nil), p.l[0]...)
+	halve(p.lInv)
+
+	return p, nil
+}
+
+// Write adds data to the message. It never returns an error.
+func (p *PMAC) Write(data []byte) (int, error) {
+	written := len(data)
+
+	if p.n > 0 {
+		n := copy(p.buf[p.n:], data)
+		p.n += n
+		data = data[n:]
+		if len(data) == 0 {
+			return written, nil
+		}
+
+		p.blocks(p.buf[:])
+		p.n = 0
+	}
+
+	// every full block but one that could be the last
+	if len(data) > BlockSize {
+		n := (len(data) - 1) / BlockSize * BlockSize
+		p.blocks(data[:n])
+		data = data[n:]
+	}
+
+	p.n = copy(p.buf[:], data)
+	return written, nil
+}
+
+// Sum appends the tag of the message written so far to b. It does not change
+// the state, so more of the message may be written afterwards.
+func (p *PMAC) Sum(b []byte) []byte {
+	sigma := p.sigma
+
+	// a full last block is masked with L/x, and a partial one padded with a
+	// single one bit
+	if p.n == BlockSize {
+		xorBytes(sigma[:], sigma[:], p.buf[:])
+		xorBytes(sigma[:], sigma[:], p.lInv)
+	} else {
+		xorBytes(sigma[:], sigma[:], p.buf[:p.n])
+		sigma[p.n] ^= 0x80
+	}
+
+	var tag [BlockSize]byte
+	p.b.Encrypt(tag[:], sigma[:])
+	return append(b, tag[:]...)
+}
+
+// Reset starts a new message under the same key
+func (p *PMAC) Reset() {
+	p.offset = [BlockSize]byte{}
+	p.sigma = [BlockSize]byte{}
+	p.i = 0
+	p.n = 0
+}
+
+// Size returns the tag size, 16 bytes
+func (p *PMAC) Size() int {
+	return BlockSize
+}
+
+// BlockSize returns the block size of the cipher, 16 bytes
+func (p *PMAC) BlockSize() int {
+	return BlockSize
+}
+
+// blocks adds data, a whole number of blocks none of which is the last, to
+// the sum, in parallel if it is long enough
+func (p *PMAC) blocks(data []byte) {
+	count := uint64(len(data) / BlockSize)
+
+	workers := p.workers
+	if limit := int(count / pmacParallelBlocks); workers > limit {
+		workers = limit
+	}
+	if workers <= 1 {
+		p.sum(p.sigma[:], p.offset[:], p.i, data)
+		p.i += count
+		return
+	}
+
+	sums := make([][BlockSize]byte, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := count * uint64(w) / uint64(workers)
+		end := count * uint64(w+1) / uint64(workers)
+
+		wg.Add(1)
+		go func(sigma []byte, start, end uint64) {
+			defer wg.Done()
+
+			var offset [BlockSize]byte
+			p.offsetAt(offset[:], p.i+start)
+			p.sum(sigma, offset[:], p.i+start, data[start*BlockSize:end*BlockSize])
+		}(sums[w][:], start, end)
+	}
+	wg.Wait()
+
+	for w := range sums {
+		xorBytes(p.sigma[:], p.sigma[:], sums[w][:])
+	}
+	p.i += count
+	p.offsetAt(p.offset[:], p.i)
+}
+
+// sum adds the encryptions of the blocks of data, which follow the first i
+// blocks of the message, to sigma, advancing offset from that of block i
+func (p *PMAC) sum(sigma, offset []byte, i uint64, data []byte) {
+	var tmp [BlockSize]byte
+	for len(data) > 0 {
+		i++
+		xorBytes(offset, offset, p.l[bits.TrailingZeros64(i)])
+		xorBytes(tmp[:], data[:BlockSize], offset)
+		p.b.Encrypt(tmp[:], tmp[:])
+		xorBytes(sigma, sigma, tmp[:])
+		data = data[BlockSize:]
+	}
+}
+
+// offsetAt writes the offset of block i to offset: the XOR of L(k) for every
+// bit k set in the Gray code of i
+func (p *PMAC) offsetAt(offset []byte, i uint64) {
+	for k := range offset {
+		offset[k] = 0
+	}
+	for k, gray := 0, i^i>>1; gray != 0; k, gray = k+1, gray>>1 {
+		if gray&1 == 1 {
+			xorBytes(offset, offset, p.l[k])
+		}
+	}
+}
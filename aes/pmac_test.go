@@ -0,0 +1,176 @@
+package aes
+
+import (
+	"hash"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Rogaway's PMAC1 test vectors for AES-128 under the key 000102...0f
+var pmacTests = []struct {
+	message string
+	tag     string
+}{
+	{
+		"",
+		"4399572cd6ea5341b8d35876a7098af7",
+	},
+	{
+		"000102",
+		"256ba5193c1b991b4df0c51f388a9e27",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"ebbd822fa458daf6dfdad7c27da76338",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f10111213",
+		"0412ca150bbf79058d8c75a58c993f55",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"e97ac04e9e5e3399ce5355cd7407bc75",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021",
+		"5cba7d5eb24f7c86ccc54604e53d5512",
+	},
+}
+
+func TestPMAC(t *testing.T) {
+	c, err := NewCipher(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	assert.NoError(t, err)
+	mac, err := NewPMAC(c)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, mac.Size())
+
+	for _, test := range pmacTests {
+		mac.Reset()
+		mac.Write(decodeHex(t, test.message))
+		assert.Equal(t, decodeHex(t, test.tag), mac.Sum(nil), test.message)
+	}
+
+	// 1000 zero bytes
+	mac.Reset()
+	mac.Write(make([]byte, 1000))
+	assert.Equal(t, decodeHex(t, "c2c9fa1d9985f6f0d2aff915a0e8d910"), mac.Sum(nil))
+}
+
+func TestPMACParallel(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	key := make([]byte, 16)
+	rng.Read(key)
+	c, err := NewCipherBackend(key, TTable)
+	assert.NoError(t, err)
+
+	serial, err := NewPMAC(c)
+	assert.NoError(t, err)
+
+	message := make([]byte, 8*pmacParallelBlocks*BlockSize+100)
+	rng.Read(message)
+
+	for _, workers := range []int{2, 3, 8} {
+		parallel, err := NewPMACParallel(c, workers)
+		assert.NoError(t, err)
+
+		for _, n := range []int{0, 16, 100, 2 * pmacParallelBlocks * BlockSize, len(message) - 1, len(message)} {
+			serial.Reset()
+			serial.Write(message[:n])
+			parallel.Reset()
+			parallel.Write(message[:n])
+			assert.Equal(t, serial.Sum(nil), parallel.Sum(nil), "workers %d length %d", workers, n)
+		}
+
+		// the message in random pieces, some long enough to split, with a
+		// Sum partway through
+		serial.Reset()
+		serial.Write(message)
+		parallel.Reset()
+		for rest := message; len(rest) > 0; {
+			k := rng.Intn(len(rest)/2 + 2)
+			if k > len(rest) {
+				k = len(rest)
+			}
+			parallel.Write(rest[:k])
+			parallel.Sum(nil)
+			rest = rest[k:]
+		}
+		assert.Equal(t, serial.Sum(nil), parallel.Sum(nil), "workers %d", workers)
+	}
+}
+
+func TestPMACOffsets(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+	mac, err := NewPMAC(c)
+	assert.NoError(t, err)
+
+	// offsetAt agrees with the offsets sum steps through one block at a time
+	offset := make([]byte, BlockSize)
+	sigma := make([]byte, BlockSize)
+	direct := make([]byte, BlockSize)
+	for i := uint64(0); i < 300; i++ {
+		mac.offsetAt(direct, i)
+		assert.Equal(t, offset, direct, "block %d", i)
+		mac.sum(sigma, offset, i, make([]byte, BlockSize))
+	}
+
+	// L/x doubles back to L
+	l := append([]byte(nil), mac.lInv...)
+	double(l)
+	assert.Equal(t, mac.l[0], l)
+}
+
+func TestPMACErrors(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	for _, workers := range []int{-1, 0} {
+		_, err = NewPMACParallel(c, workers)
+		assert.Equal(t, ErrPMACWorkers, err)
+	}
+}
+
+func BenchmarkMAC(b *testing.B) {
+	c, err := NewCipherBackend(make([]byte, 16), TTable)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	cmac, err := NewCMAC(c)
+	if err != nil {
+		b.Fatal(err)
+	}
+	pmac, err := NewPMAC(c)
+	if err != nil {
+		b.Fatal(err)
+	}
+	parallel, err := NewPMACParallel(c, runtime.GOMAXPROCS(0))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	buf := make([]byte, 1<<20)
+	for _, mac := range []struct {
+		name string
+		h    hash.Hash
+	}{
+		{"CMAC", cmac},
+		{"PMAC", pmac},
+		{"PMACParallel", parallel},
+	} {
+		b.Run(mac.name, func(b *testing.B) {
+			b.SetBytes(int64(len(buf)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				mac.h.Reset()
+				mac.h.Write(buf)
+				mac.h.Sum(nil)
+			}
+		})
+	}
+}
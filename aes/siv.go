@@ -0,0 +1,198 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"crypto/subtle"
+)
+
+// Synthetic Initialization Vector mode (RFC 5297) computes S2V, a CMAC based
+// PRF over a vector of strings, from the additional data components and the
+// plaintext. The result is both the authentication tag and the IV for Counter
+// mode encryption. Encryption is deterministic: the same inputs always give
+// the same ciphertext, which reveals only that two messages were equal. A
+// nonce is just one more additional data component, so reusing one has the
+// same mild consequence instead of GCM's loss of all security.
+
+// sivMaxComponents is the most additional data components S2V takes, RFC
+// 5297 section 7
+const sivMaxComponents = 126
+
+// SIV is AES-SIV with any number of additional data components. Its output
+// is the 16 byte synthetic IV followed by the ciphertext.
+type SIV struct {
+	mac    gocipher.Block
+	ctr    gocipher.Block
+	k1, k2 []byte
+}
+
+// NewSIV returns a SIV instance computing S2V with mac and encrypting with
+// ctr. RFC 5297 keys are the concatenation of the two, so a 256 bit
+// AES-SIV-CMAC-256 key is split into a MAC key and a CTR key of 128 bits
+// each.
+func NewSIV(mac, ctr gocipher.Block) (*SIV, error) {
+	if mac.BlockSize() != BlockSize {
+		return nil, BlockSizeError(mac.BlockSize())
+	}
+	if ctr.BlockSize() != BlockSize {
+		return nil, BlockSizeError(ctr.BlockSize())
+	}
+
+	k1, k2 := cmacSubkeys(mac)
+	return &SIV{mac: mac, ctr: ctr, k1: k1, k2: k2}, nil
+}
+
+// Seal encrypts and authenticates plaintext and authenticates each of the
+// additional data components, which may include a nonce, and appends the
+// result to dst. The order of the components matters. There may be at most
+// 126 of them.
+func (s *SIV) Seal(dst, plaintext []byte, additionalData ...[]byte) []byte {
+	if len(additionalData) > sivMaxComponents {
+		panic("aes: too many SIV additional data components")
+	}
+
+	ret, out := sliceForAppend(dst, BlockSize+len(plaintext))
+
+	var v [BlockSize]byte
+	s.s2v(v[:], additionalData, plaintext)
+
+	// plaintext may overlap out, starting where the IV goes, so it is moved
+	// into place and encrypted there
+	copy(out[BlockSize:], plaintext)
+	s.stream(v[:]).XORKeyStream(out[BlockSize:], out[BlockSize:])
+	copy(out, v[:])
+
+	return ret
+}
+
+// Open decrypts and authenticates ciphertext, authenticates the additional
+// data components and, if successful, appends the plaintext to dst. The
+// components must be the ones given to Seal, in the same order.
+func (s *SIV) Open(dst, ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(additionalData) > sivMaxComponents {
+		panic("aes: too many SIV additional data components")
+	}
+	if len(ciphertext) < BlockSize {
+		return nil, ErrAuthentication
+	}
+
+	var v [BlockSize]byte
+	copy(v[:], ciphertext)
+	ciphertext = ciphertext[BlockSize:]
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	copy(out, ciphertext)
+	s.stream(v[:]).XORKeyStream(out, out)
+
+	var expected [BlockSize]byte
+	s.s2v(expected[:], additionalData, out)
+
+	if subtle.ConstantTimeCompare(expected[:], v[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, ErrAuthentication
+	}
+
+	return ret, nil
+}
+
+// s2v writes S2V of the additional data components followed by the
+// plaintext to v, RFC 5297 section 2.4
+func (s *SIV) s2v(v []byte, additionalData [][]byte, plaintext []byte) {
+	d := make([]byte, BlockSize)
+	mac := make([]byte, BlockSize)
+
+	s.cmac(d, make([]byte, BlockSize))
+	for _, ad := range additionalData {
+		double(d)
+		s.cmac(mac, ad)
+		xorBytes(d, d, mac)
+	}
+
+	// the plaintext is XORed into the end of D if it is at least a block
+	// long, and otherwise padded and XORed with D doubled once more
+	var t []byte
+	if len(plaintext) >= BlockSize {
+		t = append([]byte(nil), plaintext...)
+		end := t[len(t)-BlockSize:]
+		xorBytes(end, end, d)
+	} else {
+		double(d)
+		t = d
+		xorBytes(t, t, plaintext)
+		t[len(plaintext)] ^= 0x80
+	}
+
+	s.cmac(v, t)
+}
+
+func (s *SIV) cmac(mac, data []byte) {
+	cmacSum(mac, s.mac, s.k1, s.k2, data)
+}
+
+// stream returns the Counter mode keystream for the synthetic IV v, which
+// starts from v with the top bit of each of its last two 32-bit words
+// cleared
+func (s *SIV) stream(v []byte) *CTR {
+	q := append([]byte(nil), v...)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+
+	stream, err := NewCTR(s.ctr, q, Counter128)
+	if err != nil {
+		panic(err)
+	}
+	return stream
+}
+
+type sivAEAD struct {
+	siv       *SIV
+	nonceSize int
+}
+
+var _ gocipher.AEAD = (*sivAEAD)(nil)
+
+// NewSIVAEAD returns AES-SIV as a crypto/cipher.AEAD, with the additional
+// data as the first S2V component and the nonce as the second. A nonceSize
+// of 0 gives deterministic encryption, in which case the nonce passed to
+// Seal and Open must be empty.
+func NewSIVAEAD(mac, ctr gocipher.Block, nonceSize int) (gocipher.AEAD, error) {
+	if nonceSize < 0 {
+		return nil, IVSizeError(nonceSize)
+	}
+
+	s, err := NewSIV(mac, ctr)
+	if err != nil {
+		return nil, err
+	}
+	return &sivAEAD{siv: s, nonceSize: nonceSize}, nil
+}
+
+func (a *sivAEAD) NonceSize() int {
+	return a.nonceSize
+}
+
+func (a *sivAEAD) Overhead() int {
+	return BlockSize
+}
+
+func (a *sivAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != a.nonceSize {
+		panic("aes: incorrect nonce length given to SIV")
+	}
+	return a.siv.Seal(dst, plaintext, a.components(nonce, additionalData)...)
+}
+
+func (a *sivAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != a.nonceSize {
+		panic("aes: incorrect nonce length given to SIV")
+	}
+	return a.siv.Open(dst, ciphertext, a.components(nonce, additionalData)...)
+}
+
+func (a *sivAEAD) components(nonce, additionalData []byte) [][]byte {
+	if a.nonceSize == 0 {
+		return [][]byte{additionalData}
+	}
+	return [][]byte{additionalData, nonce}
+}
@@ -0,0 +1,212 @@
+package aes
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 5297 appendix A, followed by cases for the other key sizes checked
+// against OpenSSL. The key is the MAC key followed by the CTR key.
+var sivTests = []struct {
+	name      string
+	key       string
+	ad        []string
+	plaintext string
+	output    string
+}{
+	{
+		"A.1 Deterministic Authenticated Encryption",
+		"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		[]string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+		"112233445566778899aabbccddee",
+		"85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+	},
+	{
+		"A.2 Nonce-Based Authenticated Encryption",
+		"7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+		[]string{
+			"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+			"102030405060708090a0",
+			"09f911029d74e35bd84156c5635688c0",
+		},
+		"7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+		"7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+	},
+	{
+		"AES-SIV-CMAC-384",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f",
+		[]string{"616263"},
+		"6869",
+		"1e55a2633783a3df5bb058fb939c6211896d",
+	},
+	{
+		"AES-SIV-CMAC-512",
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f",
+		[]string{"6164"},
+		"00112233445566778899aabbccddeeff00",
+		"9e55d661c1cefe300f7fd507c6b0ba9df3f863fd7a79fcd8278177ead3e513887f",
+	},
+}
+
+func newTestSIV(t testing.TB, key []byte) *SIV {
+	mac, err := NewCipher(key[:len(key)/2])
+	assert.NoError(t, err)
+	ctr, err := NewCipher(key[len(key)/2:])
+	assert.NoError(t, err)
+
+	s, err := NewSIV(mac, ctr)
+	assert.NoError(t, err)
+	return s
+}
+
+func TestSIV(t *testing.T) {
+	for _, test := range sivTests {
+		s := newTestSIV(t, decodeHex(t, test.key))
+
+		var ad [][]byte
+		for _, component := range test.ad {
+			ad = append(ad, decodeHex(t, component))
+		}
+		plaintext := decodeHex(t, test.plaintext)
+		output := decodeHex(t, test.output)
+
+		assert.Equal(t, output, s.Seal(nil, plaintext, ad...), test.name)
+
+		opened, err := s.Open(nil, output, ad...)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, plaintext, opened, test.name)
+	}
+}
+
+func TestSIVInPlace(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+	key := make([]byte, 32)
+	rng.Read(key)
+	s := newTestSIV(t, key)
+
+	mac, err := NewCipher(key[:16])
+	assert.NoError(t, err)
+	ctr, err := NewCipher(key[16:])
+	assert.NoError(t, err)
+	aead, err := NewSIVAEAD(mac, ctr, 12)
+	assert.NoError(t, err)
+
+	ad := make([]byte, 20)
+	nonce := make([]byte, 12)
+	rng.Read(ad)
+	rng.Read(nonce)
+	for n := 0; n <= 64; n++ {
+		plaintext := make([]byte, n)
+		rng.Read(plaintext)
+
+		// in place, over the plaintext's own storage
+		buf := make([]byte, n, n+BlockSize)
+		copy(buf, plaintext)
+		sealed := s.Seal(buf[:0], buf, ad)
+		assert.Equal(t, s.Seal(nil, plaintext, ad), sealed, "length %d", n)
+
+		opened, err := s.Open(sealed[:0], sealed, ad)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(plaintext, opened), "length %d", n)
+
+		// and through the AEAD interface
+		copy(buf, plaintext)
+		sealed = aead.Seal(buf[:0], nonce, buf, ad)
+		assert.Equal(t, aead.Seal(nil, nonce, plaintext, ad), sealed, "length %d", n)
+
+		opened, err = aead.Open(sealed[:0], nonce, sealed, ad)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(plaintext, opened), "length %d", n)
+	}
+}
+
+func TestSIVAEAD(t *testing.T) {
+	key := decodeHex(t, sivTests[0].key)
+	mac, err := NewCipher(key[:16])
+	assert.NoError(t, err)
+	ctr, err := NewCipher(key[16:])
+	assert.NoError(t, err)
+
+	// without a nonce it is the single component case of A.1
+	aead, err := NewSIVAEAD(mac, ctr, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 16, aead.Overhead())
+
+	ad := decodeHex(t, sivTests[0].ad[0])
+	plaintext := decodeHex(t, sivTests[0].plaintext)
+	sealed := aead.Seal(nil, nil, plaintext, ad)
+	assert.Equal(t, decodeHex(t, sivTests[0].output), sealed)
+
+	opened, err := aead.Open(nil, nil, sealed, ad)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	// with one the nonce is the second component
+	aead, err = NewSIVAEAD(mac, ctr, 12)
+	assert.NoError(t, err)
+	nonce := decodeHex(t, "000102030405060708090a0b")
+	s := newTestSIV(t, key)
+	assert.Equal(t, s.Seal(nil, plaintext, ad, nonce), aead.Seal(nil, nonce, plaintext, ad))
+
+	// repeating a nonce only shows that the messages were equal
+	other := aead.Seal(nil, nonce, []byte("another message"), ad)
+	assert.NotEqual(t, aead.Seal(nil, nonce, plaintext, ad)[:BlockSize], other[:BlockSize])
+	assert.Equal(t, aead.Seal(nil, nonce, plaintext, ad), aead.Seal(nil, nonce, plaintext, ad))
+
+	assert.Panics(t, func() { aead.Seal(nil, nil, plaintext, ad) })
+
+	_, err = NewSIVAEAD(mac, ctr, -1)
+	assert.Equal(t, IVSizeError(-1), err)
+}
+
+func TestSIVComponents(t *testing.T) {
+	s := newTestSIV(t, decodeHex(t, sivTests[1].key))
+	plaintext := []byte("plaintext")
+
+	// empty plaintexts and components are allowed and distinct from none
+	for _, ad := range [][][]byte{nil, {nil}, {nil, nil}, {[]byte("a"), nil}} {
+		sealed := s.Seal(nil, nil, ad...)
+		opened, err := s.Open(nil, sealed, ad...)
+		assert.NoError(t, err)
+		assert.Empty(t, opened)
+	}
+	assert.NotEqual(t, s.Seal(nil, plaintext), s.Seal(nil, plaintext, nil))
+
+	// as is the order of the components
+	a, b := []byte("first"), []byte("second")
+	sealed := s.Seal(nil, plaintext, a, b)
+	_, err := s.Open(nil, sealed, b, a)
+	assert.Equal(t, ErrAuthentication, err)
+
+	many := make([][]byte, sivMaxComponents+1)
+	assert.Panics(t, func() { s.Seal(nil, plaintext, many...) })
+	assert.NotPanics(t, func() { s.Seal(nil, plaintext, many[1:]...) })
+}
+
+func TestSIVTampering(t *testing.T) {
+	test := sivTests[1]
+	s := newTestSIV(t, decodeHex(t, test.key))
+
+	var ad [][]byte
+	for _, component := range test.ad {
+		ad = append(ad, decodeHex(t, component))
+	}
+	sealed := decodeHex(t, test.output)
+
+	for i := range sealed {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x01
+
+		opened, err := s.Open(nil, tampered, ad...)
+		assert.Equal(t, ErrAuthentication, err, "byte %d", i)
+		assert.Nil(t, opened)
+	}
+
+	_, err := s.Open(nil, sealed, ad[:2]...)
+	assert.Equal(t, ErrAuthentication, err)
+	_, err = s.Open(nil, sealed[:15], ad...)
+	assert.Equal(t, ErrAuthentication, err)
+}
@@ -0,0 +1,152 @@
+package aes
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Direction tells a Tracer which way a block is travelling through AES
+type Direction int
+
+const (
+	// Forward is the Cipher of FIPS 197 section 5.1 (encryption)
+	Forward Direction = iota
+	// Inverse is the Inverse Cipher of FIPS 197 section 5.3 (decryption)
+	Inverse
+	// EquivalentInverse is the Equivalent Inverse Cipher of FIPS 197 section
+	// 5.3.5 (decryption)
+	EquivalentInverse
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Forward:
+		return "encrypt"
+	case Inverse:
+		return "decrypt"
+	case EquivalentInverse:
+		return "decrypt-equivalent"
+	}
+	return "Direction(" + strconv.Itoa(int(d)) + ")"
+}
+
+func (d Direction) inverse() bool {
+	return d == Inverse || d == EquivalentInverse
+}
+
+// Tracer observes the intermediate values computed while a block is encrypted
+// or decrypted. Rounds are numbered and steps named the same way as in the
+// FIPS 197 Appendix C listings (for example round 3 "s_box" or round 9
+// "ik_add"). Blocks are passed as 16 bytes in input order and are only valid
+// for the duration of the call.
+type Tracer interface {
+	// OnInput is called once per block before any transformation, keyLen is
+	// the length of the cipher key in bytes
+	OnInput(dir Direction, keyLen int, in []byte)
+	// OnRoundStep is called with the state after each transformation
+	OnRoundStep(round int, stepName string, state []byte)
+	// OnRoundKey is called with the round key each time one is added
+	OnRoundKey(round int, stepName string, key []byte)
+	// OnOutput is called once per block with the result of the final round
+	OnOutput(round int, out []byte)
+}
+
+// NopTracer discards everything it is given. A nil Tracer behaves the same
+// and is cheaper, as nothing is prepared for it.
+type NopTracer struct{}
+
+// OnInput does nothing
+func (NopTracer) OnInput(dir Direction, keyLen int, in []byte) {}
+
+// OnRoundStep does nothing
+func (NopTracer) OnRoundStep(round int, stepName string, state []byte) {}
+
+// OnRoundKey does nothing
+func (NopTracer) OnRoundKey(round int, stepName string, key []byte) {}
+
+// OnOutput does nothing
+func (NopTracer) OnOutput(round int, out []byte) {}
+
+// TextTracer writes the round by round listing in the format of FIPS 197
+// Appendix C
+type TextTracer struct {
+	w   io.Writer
+	dir Direction
+}
+
+// NewTextTracer returns a TextTracer that writes to w
+func NewTextTracer(w io.Writer) *TextTracer {
+	return &TextTracer{w: w}
+}
+
+// OnInput writes the listing header and the input block
+func (t *TextTracer) OnInput(dir Direction, keyLen int, in []byte) {
+	t.dir = dir
+
+	switch dir {
+	case Inverse:
+		fmt.Fprintf(t.w, "INVERSE CIPHER (DECRYPT):\n")
+	case EquivalentInverse:
+		fmt.Fprintf(t.w, "EQUIVALENT INVERSE CIPHER (DECRYPT):\n")
+	default:
+		fmt.Fprintf(t.w, "CIPHER (ENCRYPT):\n")
+	}
+
+	if dir.inverse() {
+		t.line(0, "iinput", in)
+	} else {
+		t.line(0, "input", in)
+	}
+}
+
+// OnRoundStep writes the state after a transformation
+func (t *TextTracer) OnRoundStep(round int, stepName string, state []byte) {
+	t.line(round, stepName, state)
+}
+
+// OnRoundKey writes a round key
+func (t *TextTracer) OnRoundKey(round int, stepName string, key []byte) {
+	t.line(round, stepName, key)
+}
+
+// OnOutput writes the output block followed by a blank line
+func (t *TextTracer) OnOutput(round int, out []byte) {
+	if t.dir.inverse() {
+		t.line(round, "ioutput", out)
+	} else {
+		t.line(round, "output", out)
+	}
+	fmt.Fprintln(t.w)
+}
+
+func (t *TextTracer) line(round int, stepName string, b []byte) {
+	fmt.Fprintf(t.w, "round[%2d].%-9s%x\n", round, stepName, b)
+}
+
+// The trace helpers only build what a Tracer is handed when there is one, so
+// that untraced blocks are not slowed down or allocated for
+
+func traceInput(t Tracer, dir Direction, Nr int, in []byte) {
+	if t != nil {
+		t.OnInput(dir, 4*(Nr-6), in)
+	}
+}
+
+func traceStep(t Tracer, round int, stepName string, s *state) {
+	if t != nil {
+		t.OnRoundStep(round, stepName, s[:])
+	}
+}
+
+func traceKey(t Tracer, round int, stepName string, w []uint32) {
+	if t != nil {
+		t.OnRoundKey(round, stepName, wordsToBytes(w))
+	}
+}
+
+func traceOutput(t Tracer, Nr int, s *state) {
+	if t != nil {
+		t.OnOutput(Nr, s[:])
+	}
+}
@@ -0,0 +1,163 @@
+package aes
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TraceRecord is a single line of a JSON Lines trace. Every input, round key,
+// transformation and output of a block becomes one record, with the round and
+// step named as in the FIPS 197 Appendix C listings (for example "iinput",
+// "k_sch" or "is_box"). State holds the 16 byte block, or the round key for
+// "k_sch" and "ik_sch" records, as lower case hex.
+type TraceRecord struct {
+	Round     int       `json:"round"`
+	Step      string    `json:"step"`
+	KeySize   int       `json:"key_size"`
+	Direction Direction `json:"direction"`
+	State     string    `json:"state"`
+}
+
+// StateBytes decodes the hex State of the record
+func (r TraceRecord) StateBytes() ([]byte, error) {
+	b, err := hex.DecodeString(r.State)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != BlockSize {
+		return nil, fmt.Errorf("aes: trace state is %d bytes, want %d", len(b), BlockSize)
+	}
+	return b, nil
+}
+
+// MarshalText encodes the direction as "encrypt", "decrypt" or
+// "decrypt-equivalent"
+func (d Direction) MarshalText() ([]byte, error) {
+	switch d {
+	case Forward, Inverse, EquivalentInverse:
+		return []byte(d.String()), nil
+	}
+	return nil, errors.New("aes: unknown direction " + d.String())
+}
+
+// UnmarshalText decodes a direction written by MarshalText
+func (d *Direction) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "encrypt":
+		*d = Forward
+	case "decrypt":
+		*d = Inverse
+	case "decrypt-equivalent":
+		*d = EquivalentInverse
+	default:
+		return fmt.Errorf("aes: unknown direction %q", text)
+	}
+	return nil
+}
+
+// JSONTracer writes a TraceRecord per line (JSON Lines) for every value it
+// observes. Write errors stop the trace and are reported by Err.
+type JSONTracer struct {
+	enc     *json.Encoder
+	dir     Direction
+	keySize int
+	err     error
+}
+
+// NewJSONTracer returns a JSONTracer that writes to w
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error encountered while writing the trace
+func (t *JSONTracer) Err() error {
+	return t.err
+}
+
+// OnInput records the input block
+func (t *JSONTracer) OnInput(dir Direction, keyLen int, in []byte) {
+	t.dir = dir
+	t.keySize = 8 * keyLen
+
+	if dir.inverse() {
+		t.write(0, "iinput", in)
+	} else {
+		t.write(0, "input", in)
+	}
+}
+
+// OnRoundStep records the state after a transformation
+func (t *JSONTracer) OnRoundStep(round int, stepName string, state []byte) {
+	t.write(round, stepName, state)
+}
+
+// OnRoundKey records a round key
+func (t *JSONTracer) OnRoundKey(round int, stepName string, key []byte) {
+	t.write(round, stepName, key)
+}
+
+// OnOutput records the output block
+func (t *JSONTracer) OnOutput(round int, out []byte) {
+	if t.dir.inverse() {
+		t.write(round, "ioutput", out)
+	} else {
+		t.write(round, "output", out)
+	}
+}
+
+func (t *JSONTracer) write(round int, stepName string, b []byte) {
+	if t.err != nil {
+		return
+	}
+
+	t.err = t.enc.Encode(TraceRecord{
+		Round:     round,
+		Step:      stepName,
+		KeySize:   t.keySize,
+		Direction: t.dir,
+		State:     hex.EncodeToString(b),
+	})
+}
+
+// TraceDecoder reads the records written by a JSONTracer back in
+type TraceDecoder struct {
+	dec *json.Decoder
+}
+
+// NewTraceDecoder returns a TraceDecoder that reads from r
+func NewTraceDecoder(r io.Reader) *TraceDecoder {
+	return &TraceDecoder{dec: json.NewDecoder(r)}
+}
+
+// Decode reads the next record. It returns io.EOF once the trace is
+// exhausted and an error for records whose state is not a 16 byte hex block.
+func (d *TraceDecoder) Decode() (TraceRecord, error) {
+	var r TraceRecord
+	if err := d.dec.Decode(&r); err != nil {
+		return TraceRecord{}, err
+	}
+	if _, err := r.StateBytes(); err != nil {
+		return TraceRecord{}, err
+	}
+	return r, nil
+}
+
+// ReadTrace decodes every record in r
+func ReadTrace(r io.Reader) ([]TraceRecord, error) {
+	d := NewTraceDecoder(r)
+
+	var records []TraceRecord
+	for {
+		rec, err := d.Decode()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
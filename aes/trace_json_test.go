@@ -0,0 +1,103 @@
+package aes
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONTracer(t *testing.T) {
+	in := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	key := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17}
+
+	var buf bytes.Buffer
+	tracer := NewJSONTracer(&buf)
+
+	out, err := EncryptTrace(in, key, tracer)
+	assert.NoError(t, err)
+	_, err = DecryptTrace(out, key, tracer)
+	assert.NoError(t, err)
+	assert.NoError(t, tracer.Err())
+
+	assert.Equal(t, `{"round":1,"step":"s_box","key_size":192,"direction":"encrypt","state":"63cab7040953d051cd60e0e7ba70e18c"}`,
+		strings.Split(buf.String(), "\n")[3])
+
+	records, err := ReadTrace(&buf)
+	assert.NoError(t, err)
+
+	// input, round 0 key, 11 full rounds, the final round and the output, for
+	// each direction
+	assert.Len(t, records, 2*(1+1+11*5+4+1))
+
+	assert.Equal(t, TraceRecord{Round: 0, Step: "input", KeySize: 192, Direction: Forward,
+		State: "00112233445566778899aabbccddeeff"}, records[0])
+	assert.Equal(t, TraceRecord{Round: 12, Step: "output", KeySize: 192, Direction: Forward,
+		State: "dda97ca4864cdfe06eaf70a0ec0d7191"}, records[61])
+	assert.Equal(t, TraceRecord{Round: 1, Step: "ik_add", KeySize: 192, Direction: Inverse,
+		State: "71d720933b6d677dc00b8f28238e0fb7"}, records[62+6])
+	assert.Equal(t, TraceRecord{Round: 12, Step: "ioutput", KeySize: 192, Direction: Inverse,
+		State: "00112233445566778899aabbccddeeff"}, records[123])
+
+	state, err := records[123].StateBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, in, state)
+}
+
+func TestJSONTracerBlocks(t *testing.T) {
+	c, err := NewCipher(make([]byte, 16))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	tracer := NewJSONTracer(&buf)
+	c.SetTracer(tracer)
+
+	// a Cipher given more than one block, by EncryptBlocks or directly,
+	// traces one block per call
+	blocks := make([]byte, 4*BlockSize)
+	c.EncryptBlocks(blocks, blocks)
+	c.Decrypt(blocks, blocks)
+	c.DecryptEquivalent(blocks, blocks)
+	assert.NoError(t, tracer.Err())
+
+	records, err := ReadTrace(&buf)
+	assert.NoError(t, err)
+
+	inputs := 0
+	for _, record := range records {
+		_, err := record.StateBytes()
+		assert.NoError(t, err, "%d %s", record.Round, record.Step)
+		if record.Round == 0 && strings.HasSuffix(record.Step, "input") {
+			inputs++
+		}
+	}
+	assert.Equal(t, 6, inputs)
+}
+
+func TestTraceDecoderErrors(t *testing.T) {
+	_, err := ReadTrace(strings.NewReader(`{"round":0,"step":"input","key_size":128,"direction":"sideways","state":"00112233445566778899aabbccddeeff"}`))
+	assert.Error(t, err)
+
+	_, err = ReadTrace(strings.NewReader(`{"round":0,"step":"input","key_size":128,"direction":"encrypt","state":"0011"}`))
+	assert.Error(t, err)
+
+	_, err = ReadTrace(strings.NewReader(`{"round":0,"step":"input","key_size":128,"direction":"encrypt","state":"zz112233445566778899aabbccddeeff"}`))
+	assert.Error(t, err)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestJSONTracerWriteError(t *testing.T) {
+	tracer := NewJSONTracer(failingWriter{})
+
+	_, err := EncryptTrace(make([]byte, 16), make([]byte, 16), tracer)
+	assert.NoError(t, err)
+	assert.EqualError(t, tracer.Err(), "disk full")
+}
@@ -0,0 +1,104 @@
+package aes
+
+import "encoding/binary"
+
+// The T-tables fold SubBytes and MixColumns into one 32-bit lookup per state
+// byte. te0[x] is the column MixColumns produces from a column holding S[x] in
+// row 0 and zeroes elsewhere, te1-te3 are the same column for rows 1-3 (te0
+// rotated right by 8, 16 and 24 bits). td0-td3 do the same for InvSubBytes and
+// InvMixColumns.
+var (
+	te0, te1, te2, te3 [256]uint32
+	td0, td1, td2, td3 [256]uint32
+
+	// flat copies of sbox and invsbox for the final round
+	sbox0, invsbox0 [256]byte
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		s := sbox[i>>4][i&0x0f]
+		sbox0[i] = s
+
+		w := uint32(ffMultiply(0x02, s))<<24 | uint32(s)<<16 | uint32(s)<<8 | uint32(ffMultiply(0x03, s))
+		te0[i] = w
+		te1[i] = w>>8 | w<<24
+		te2[i] = w>>16 | w<<16
+		te3[i] = w>>24 | w<<8
+
+		is := invsbox[i>>4][i&0x0f]
+		invsbox0[i] = is
+
+		w = uint32(ffMultiply(0x0e, is))<<24 | uint32(ffMultiply(0x09, is))<<16 |
+			uint32(ffMultiply(0x0d, is))<<8 | uint32(ffMultiply(0x0b, is))
+		td0[i] = w
+		td1[i] = w>>8 | w<<24
+		td2[i] = w>>16 | w<<16
+		td3[i] = w>>24 | w<<8
+	}
+}
+
+// encryptBlockTTable encrypts one block from src into dst with the key
+// schedule w. Each of s0-s3 holds one column of the state, row 0 in the most
+// significant byte, so ShiftRows becomes a choice of which column each row's
+// byte is taken from.
+func encryptBlockTTable(w []uint32, dst, src []byte) {
+	Nr := (len(w) - 1) / 4
+
+	s0 := binary.BigEndian.Uint32(src[0:4]) ^ w[0]
+	s1 := binary.BigEndian.Uint32(src[4:8]) ^ w[1]
+	s2 := binary.BigEndian.Uint32(src[8:12]) ^ w[2]
+	s3 := binary.BigEndian.Uint32(src[12:16]) ^ w[3]
+
+	k := 4
+	for round := 1; round < Nr; round++ {
+		t0 := te0[s0>>24] ^ te1[s1>>16&0xff] ^ te2[s2>>8&0xff] ^ te3[s3&0xff] ^ w[k]
+		t1 := te0[s1>>24] ^ te1[s2>>16&0xff] ^ te2[s3>>8&0xff] ^ te3[s0&0xff] ^ w[k+1]
+		t2 := te0[s2>>24] ^ te1[s3>>16&0xff] ^ te2[s0>>8&0xff] ^ te3[s1&0xff] ^ w[k+2]
+		t3 := te0[s3>>24] ^ te1[s0>>16&0xff] ^ te2[s1>>8&0xff] ^ te3[s2&0xff] ^ w[k+3]
+		s0, s1, s2, s3 = t0, t1, t2, t3
+		k += 4
+	}
+
+	// the final round has no MixColumns so it goes back to plain S-box lookups
+	t0 := uint32(sbox0[s0>>24])<<24 | uint32(sbox0[s1>>16&0xff])<<16 | uint32(sbox0[s2>>8&0xff])<<8 | uint32(sbox0[s3&0xff])
+	t1 := uint32(sbox0[s1>>24])<<24 | uint32(sbox0[s2>>16&0xff])<<16 | uint32(sbox0[s3>>8&0xff])<<8 | uint32(sbox0[s0&0xff])
+	t2 := uint32(sbox0[s2>>24])<<24 | uint32(sbox0[s3>>16&0xff])<<16 | uint32(sbox0[s0>>8&0xff])<<8 | uint32(sbox0[s1&0xff])
+	t3 := uint32(sbox0[s3>>24])<<24 | uint32(sbox0[s0>>16&0xff])<<16 | uint32(sbox0[s1>>8&0xff])<<8 | uint32(sbox0[s2&0xff])
+
+	binary.BigEndian.PutUint32(dst[0:4], t0^w[k])
+	binary.BigEndian.PutUint32(dst[4:8], t1^w[k+1])
+	binary.BigEndian.PutUint32(dst[8:12], t2^w[k+2])
+	binary.BigEndian.PutUint32(dst[12:16], t3^w[k+3])
+}
+
+// decryptBlockTTable decrypts one block from src into dst with the
+// Equivalent Inverse Cipher key schedule dw
+func decryptBlockTTable(dw []uint32, dst, src []byte) {
+	Nr := (len(dw) - 1) / 4
+
+	k := 4 * Nr
+	s0 := binary.BigEndian.Uint32(src[0:4]) ^ dw[k]
+	s1 := binary.BigEndian.Uint32(src[4:8]) ^ dw[k+1]
+	s2 := binary.BigEndian.Uint32(src[8:12]) ^ dw[k+2]
+	s3 := binary.BigEndian.Uint32(src[12:16]) ^ dw[k+3]
+
+	for round := Nr - 1; round > 0; round-- {
+		k -= 4
+		t0 := td0[s0>>24] ^ td1[s3>>16&0xff] ^ td2[s2>>8&0xff] ^ td3[s1&0xff] ^ dw[k]
+		t1 := td0[s1>>24] ^ td1[s0>>16&0xff] ^ td2[s3>>8&0xff] ^ td3[s2&0xff] ^ dw[k+1]
+		t2 := td0[s2>>24] ^ td1[s1>>16&0xff] ^ td2[s0>>8&0xff] ^ td3[s3&0xff] ^ dw[k+2]
+		t3 := td0[s3>>24] ^ td1[s2>>16&0xff] ^ td2[s1>>8&0xff] ^ td3[s0&0xff] ^ dw[k+3]
+		s0, s1, s2, s3 = t0, t1, t2, t3
+	}
+
+	t0 := uint32(invsbox0[s0>>24])<<24 | uint32(invsbox0[s3>>16&0xff])<<16 | uint32(invsbox0[s2>>8&0xff])<<8 | uint32(invsbox0[s1&0xff])
+	t1 := uint32(invsbox0[s1>>24])<<24 | uint32(invsbox0[s0>>16&0xff])<<16 | uint32(invsbox0[s3>>8&0xff])<<8 | uint32(invsbox0[s2&0xff])
+	t2 := uint32(invsbox0[s2>>24])<<24 | uint32(invsbox0[s1>>16&0xff])<<16 | uint32(invsbox0[s0>>8&0xff])<<8 | uint32(invsbox0[s3&0xff])
+	t3 := uint32(invsbox0[s3>>24])<<24 | uint32(invsbox0[s2>>16&0xff])<<16 | uint32(invsbox0[s1>>8&0xff])<<8 | uint32(invsbox0[s0&0xff])
+
+	binary.BigEndian.PutUint32(dst[0:4], t0^dw[0])
+	binary.BigEndian.PutUint32(dst[4:8], t1^dw[1])
+	binary.BigEndian.PutUint32(dst[8:12], t2^dw[2])
+	binary.BigEndian.PutUint32(dst[12:16], t3^dw[3])
+}
@@ -0,0 +1,84 @@
+package aes
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTables(t *testing.T) {
+	assert.Equal(t, uint32(0xc66363a5), te0[0x00])
+	assert.Equal(t, uint32(0xa5c66363), te1[0x00])
+	assert.Equal(t, uint32(0x51f4a750), td0[0x00])
+	assert.Equal(t, uint32(0x5051f4a7), td1[0x00])
+}
+
+func TestTTableMatchesReference(t *testing.T) {
+	rng := rand.New(rand.NewSource(465))
+
+	for _, keyLen := range []int{16, 24, 32} {
+		key := make([]byte, keyLen)
+		in := make([]byte, BlockSize)
+
+		for i := 0; i < 100; i++ {
+			rng.Read(key)
+			rng.Read(in)
+
+			reference, err := NewCipher(key)
+			assert.NoError(t, err)
+			fast, err := NewCipherBackend(key, TTable)
+			assert.NoError(t, err)
+
+			expected := make([]byte, BlockSize)
+			out := make([]byte, BlockSize)
+
+			reference.Encrypt(expected, in)
+			fast.Encrypt(out, in)
+			assert.Equal(t, expected, out)
+
+			reference.Decrypt(expected, in)
+			fast.Decrypt(out, in)
+			assert.Equal(t, expected, out)
+		}
+	}
+}
+
+func TestBadBackend(t *testing.T) {
+	_, err := NewCipherBackend(make([]byte, 16), Backend(42))
+	assert.Equal(t, BackendError(42), err)
+}
+
+func benchmarkBackends(b *testing.B, decrypt bool) {
+	for _, backend := range []Backend{Reference, TTable, Bitsliced} {
+		for _, keyLen := range []int{16, 24, 32} {
+			b.Run(backend.String()+"/AES-"+strconv.Itoa(8*keyLen), func(b *testing.B) {
+				c, err := NewCipherBackend(make([]byte, keyLen), backend)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				buf := make([]byte, BlockSize)
+				b.SetBytes(BlockSize)
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if decrypt {
+						c.Decrypt(buf, buf)
+					} else {
+						c.Encrypt(buf, buf)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	benchmarkBackends(b, false)
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	benchmarkBackends(b, true)
+}
@@ -0,0 +1,67 @@
+package aes
+
+import (
+	"bytes"
+	"hash"
+)
+
+// XCBC (RFC 3566), the predecessor of CMAC, masks the last block of a CBC-MAC
+// the same way, but with three keys derived by encrypting constant blocks
+// under the user's key: K1 keys the CBC-MAC itself, K2 masks a full last
+// block and K3 a padded one. It is therefore a CMAC computation with K1 as
+// the cipher key and K2 and K3 as the subkeys.
+
+const (
+	xcbcMACKeySize = 16
+	xcbcMAC96Size  = 12
+)
+
+// NewXCBCMAC96 returns AES-XCBC-MAC-96 of RFC 3566 under key, which must be
+// 16 bytes. Its tags are the leading 12 bytes of the XCBC-MAC.
+func NewXCBCMAC96(key []byte) (hash.Hash, error) {
+	if len(key) != xcbcMACKeySize {
+		return nil, KeySizeError(len(key))
+	}
+	return newXCBC(key, xcbcMAC96Size)
+}
+
+// NewXCBCPRF128 returns AES-XCBC-PRF-128 of RFC 4434, the full 16 byte
+// XCBC-MAC under a key of any length. Shorter keys are padded with zeros to
+// 16 bytes, and longer ones replaced by their own XCBC-MAC under the zero
+// key.
+func NewXCBCPRF128(key []byte) (hash.Hash, error) {
+	if len(key) != xcbcMACKeySize {
+		k := make([]byte, xcbcMACKeySize)
+		if len(key) < xcbcMACKeySize {
+			copy(k, key)
+		} else {
+			mac, err := newXCBC(k, BlockSize)
+			if err != nil {
+				return nil, err
+			}
+			mac.Write(key)
+			k = mac.Sum(k[:0])
+		}
+		key = k
+	}
+	return newXCBC(key, BlockSize)
+}
+
+func newXCBC(key []byte, tagSize int) (*CMAC, error) {
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k := make([][]byte, 3)
+	for i := range k {
+		k[i] = bytes.Repeat([]byte{byte(i + 1)}, BlockSize)
+		c.Encrypt(k[i], k[i])
+	}
+
+	k1, err := NewCipher(k[0])
+	if err != nil {
+		return nil, err
+	}
+	return &CMAC{b: k1, k1: k[1], k2: k[2], tagSize: tagSize}, nil
+}
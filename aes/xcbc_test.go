@@ -0,0 +1,109 @@
+package aes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 3566 section 4.6 under the key 000102...0f, with the full 128 bit
+// AES-XCBC-MAC of which AES-XCBC-MAC-96 is the first 12 bytes
+var xcbcTests = []struct {
+	message string
+	mac     string
+}{
+	{
+		"",
+		"75f0251d528ac01c4573dfd584d79f29",
+	},
+	{
+		"000102",
+		"5b376580ae2f19afe7219ceef172756f",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"d2a246fa349b68a79998a4394ff7a263",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f10111213",
+		"47f51b4564966215b8985c63055ed308",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		"f54f0ec8d2b9f3d36807734bd5283fd4",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021",
+		"becbb3bccdb518a30677d5481fb6b4d8",
+	},
+}
+
+// RFC 4434 section 5, keys shorter than, equal to and longer than 16 bytes
+// with the message 000102...13
+var xcbcPRFTests = []struct {
+	key string
+	prf string
+}{
+	{
+		"000102030405060708090a0b0c0d0e0f",
+		"47f51b4564966215b8985c63055ed308",
+	},
+	{
+		"00010203040506070809",
+		"0fa087af7d866e7653434e602fdde835",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0fedcb",
+		"8cd3c93ae598a9803006ffb67c40e9e4",
+	},
+}
+
+func TestXCBCMAC96(t *testing.T) {
+	mac, err := NewXCBCMAC96(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	assert.NoError(t, err)
+	assert.Equal(t, 12, mac.Size())
+
+	for _, test := range xcbcTests {
+		mac.Reset()
+		mac.Write(decodeHex(t, test.message))
+		assert.Equal(t, decodeHex(t, test.mac)[:12], mac.Sum(nil), test.message)
+	}
+
+	// RFC 3566 test case 7, 1000 zero bytes
+	mac.Reset()
+	mac.Write(make([]byte, 1000))
+	assert.Equal(t, decodeHex(t, "f0dafee895db30253761103b5d84528f")[:12], mac.Sum(nil))
+}
+
+func TestXCBCPRF128(t *testing.T) {
+	message := decodeHex(t, "000102030405060708090a0b0c0d0e0f10111213")
+
+	for _, test := range xcbcPRFTests {
+		prf, err := NewXCBCPRF128(decodeHex(t, test.key))
+		assert.NoError(t, err)
+		assert.Equal(t, 16, prf.Size())
+
+		prf.Write(message)
+		assert.Equal(t, decodeHex(t, test.prf), prf.Sum(nil), test.key)
+	}
+
+	// under a 16 byte key the PRF is the untruncated MAC
+	prf, err := NewXCBCPRF128(decodeHex(t, "000102030405060708090a0b0c0d0e0f"))
+	assert.NoError(t, err)
+	for _, test := range xcbcTests {
+		prf.Reset()
+		prf.Write(decodeHex(t, test.message))
+		assert.Equal(t, decodeHex(t, test.mac), prf.Sum(nil), test.message)
+	}
+}
+
+func TestXCBCErrors(t *testing.T) {
+	for _, n := range []int{0, 15, 17, 24, 32} {
+		_, err := NewXCBCMAC96(make([]byte, n))
+		assert.Equal(t, KeySizeError(n), err)
+	}
+
+	// the PRF takes any key, including an empty one
+	_, err := NewXCBCPRF128(nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,137 @@
+package aes
+
+import (
+	gocipher "crypto/cipher"
+	"encoding/binary"
+)
+
+// XTS (IEEE 1619, SP 800-38E) encrypts each sector of a disk independently.
+// The tweak key encrypts the sector number into a tweak, which is multiplied
+// by alpha for every block of the sector, and each block is encrypted with
+// the data key between two XORs of its tweak. A sector whose length is not a
+// whole number of blocks borrows the tail of its last full ciphertext block
+// to pad the partial one, so the ciphertext is exactly as long as the
+// plaintext.
+//
+// XTS provides no integrity: a change to the ciphertext goes undetected and
+// randomizes the block it falls in.
+
+// XTS is an XTS-AES instance made of two independent keys
+type XTS struct {
+	data  gocipher.Block
+	tweak gocipher.Block
+}
+
+// NewXTS returns an XTS instance encrypting data with data and sector
+// numbers with tweak. IEEE 1619 keys are the concatenation of the two, so a
+// 256 bit XTS-AES-128 key is split into a data key and a tweak key of 128
+// bits each.
+func NewXTS(data, tweak gocipher.Block) (*XTS, error) {
+	if data.BlockSize() != BlockSize {
+		return nil, BlockSizeError(data.BlockSize())
+	}
+	if tweak.BlockSize() != BlockSize {
+		return nil, BlockSizeError(tweak.BlockSize())
+	}
+	return &XTS{data: data, tweak: tweak}, nil
+}
+
+// EncryptSector encrypts the sector src with the given sector number into
+// dst. src may be any length of at least one block, and dst must be at least
+// as long. dst and src may be the same slice.
+func (x *XTS) EncryptSector(dst, src []byte, sector uint64) {
+	x.cryptSector(dst, src, sector, false)
+}
+
+// DecryptSector is the inverse of EncryptSector
+func (x *XTS) DecryptSector(dst, src []byte, sector uint64) {
+	x.cryptSector(dst, src, sector, true)
+}
+
+// EncryptSectors encrypts src as consecutive sectors of sectorSize bytes, the
+// first numbered first. The length of src must be a multiple of sectorSize.
+func (x *XTS) EncryptSectors(dst, src []byte, sectorSize int, first uint64) {
+	x.cryptSectors(dst, src, sectorSize, first, false)
+}
+
+// DecryptSectors is the inverse of EncryptSectors
+func (x *XTS) DecryptSectors(dst, src []byte, sectorSize int, first uint64) {
+	x.cryptSectors(dst, src, sectorSize, first, true)
+}
+
+func (x *XTS) cryptSectors(dst, src []byte, sectorSize int, first uint64, decrypt bool) {
+	if sectorSize < BlockSize || len(src)%sectorSize != 0 {
+		panic("aes: input not whole sectors")
+	}
+	if len(dst) < len(src) {
+		panic("aes: output smaller than input")
+	}
+
+	for sector := first; len(src) > 0; sector++ {
+		x.cryptSector(dst[:sectorSize], src[:sectorSize], sector, decrypt)
+		src = src[sectorSize:]
+		dst = dst[sectorSize:]
+	}
+}
+
+func (x *XTS) cryptSector(dst, src []byte, sector uint64, decrypt bool) {
+	if len(src) < BlockSize {
+		panic("aes: XTS input shorter than one block")
+	}
+	if len(dst) < len(src) {
+		panic("aes: output smaller than input")
+	}
+
+	// the tweak is the sector number as a 128 bit little-endian integer
+	var tweak [BlockSize]byte
+	binary.LittleEndian.PutUint64(tweak[:8], sector)
+	x.tweak.Encrypt(tweak[:], tweak[:])
+
+	// the last full block is set aside when there is a partial block after it
+	partial := len(src) % BlockSize
+	full := len(src) - partial
+	if partial != 0 {
+		full -= BlockSize
+	}
+
+	for i := 0; i < full; i += BlockSize {
+		x.cryptBlock(dst[i:i+BlockSize], src[i:i+BlockSize], tweak[:], decrypt)
+		mulAlpha(tweak[:])
+	}
+
+	if partial == 0 {
+		return
+	}
+
+	// ciphertext stealing. Encryption runs the last full block under tweak
+	// m-1 and the partial block padded with the stolen tail under tweak m;
+	// decryption has to undo them in the opposite order, so it needs tweak m
+	// before tweak m-1.
+	last := tweak
+	mulAlpha(last[:])
+	first, second := tweak[:], last[:]
+	if decrypt {
+		first, second = second, first
+	}
+
+	var cc [BlockSize]byte
+	x.cryptBlock(cc[:], src[full:full+BlockSize], first, decrypt)
+
+	var pp [BlockSize]byte
+	copy(pp[:], src[full+BlockSize:])
+	copy(pp[partial:], cc[partial:])
+
+	copy(dst[full+BlockSize:], cc[:partial])
+	x.cryptBlock(dst[full:full+BlockSize], pp[:], second, decrypt)
+}
+
+// cryptBlock encrypts or decrypts one block between two XORs of tweak
+func (x *XTS) cryptBlock(dst, src, tweak []byte, decrypt bool) {
+	xorBytes(dst, src, tweak)
+	if decrypt {
+		x.data.Decrypt(dst, dst)
+	} else {
+		x.data.Encrypt(dst, dst)
+	}
+	xorBytes(dst, dst, tweak)
+}